@@ -1,27 +1,66 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"forum/db"
+	"forum/internal/config"
+	bandeli "forum/internal/pkg/bans/delivery"
+	banrepo "forum/internal/pkg/bans/repository"
+	banusec "forum/internal/pkg/bans/usecase"
+	"forum/internal/pkg/cursor"
+	evntdeli "forum/internal/pkg/events/delivery"
+	evnthub "forum/internal/pkg/events/hub"
+	evntrepo "forum/internal/pkg/events/repository"
+	evntusec "forum/internal/pkg/events/usecase"
+	fltrdeli "forum/internal/pkg/filters/delivery"
+	fltrrepo "forum/internal/pkg/filters/repository"
+	fltrusec "forum/internal/pkg/filters/usecase"
 	forumdeli "forum/internal/pkg/forum/delivery"
 	forumrepo "forum/internal/pkg/forum/repository"
 	forumusec "forum/internal/pkg/forum/usecase"
+	hlthdeli "forum/internal/pkg/health/delivery"
+	hlthrepo "forum/internal/pkg/health/repository"
+	hlthusec "forum/internal/pkg/health/usecase"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
 	"forum/internal/pkg/middleware"
+	moddeli "forum/internal/pkg/moderators/delivery"
+	modrepo "forum/internal/pkg/moderators/repository"
+	modusec "forum/internal/pkg/moderators/usecase"
 
 	thrddeli "forum/internal/pkg/threads/delivery"
 	thrdrepo "forum/internal/pkg/threads/repository"
 	thrdusec "forum/internal/pkg/threads/usecase"
 
+	ntfydeli "forum/internal/pkg/notifications/delivery"
+	ntfyrepo "forum/internal/pkg/notifications/repository"
+	ntfyusec "forum/internal/pkg/notifications/usecase"
+
 	postdeli "forum/internal/pkg/posts/delivery"
 	postrepo "forum/internal/pkg/posts/repository"
 	postusec "forum/internal/pkg/posts/usecase"
 
+	rprtdeli "forum/internal/pkg/reports/delivery"
+	rprtrepo "forum/internal/pkg/reports/repository"
+	rprtusec "forum/internal/pkg/reports/usecase"
+
+	srchdeli "forum/internal/pkg/search/delivery"
+	srchrepo "forum/internal/pkg/search/repository"
+	srchusec "forum/internal/pkg/search/usecase"
+
 	srvcdeli "forum/internal/pkg/service/delivery"
 	srvcrepo "forum/internal/pkg/service/repository"
 	srvcusec "forum/internal/pkg/service/usecase"
 
 	_ "github.com/jackc/pgx/stdlib"
 
+	tokndeli "forum/internal/pkg/tokens/delivery"
+	toknrepo "forum/internal/pkg/tokens/repository"
+	toknusec "forum/internal/pkg/tokens/usecase"
+
 	userdeli "forum/internal/pkg/user/delivery"
 	userrepo "forum/internal/pkg/user/repository"
 	userusec "forum/internal/pkg/user/usecase"
@@ -29,56 +68,219 @@ import (
 	votedeli "forum/internal/pkg/votes/delivery"
 	voterepo "forum/internal/pkg/votes/repository"
 	voteusec "forum/internal/pkg/votes/usecase"
+
+	hookdeli "forum/internal/pkg/webhooks/delivery"
+	hookdisp "forum/internal/pkg/webhooks/dispatcher"
+	hookrepo "forum/internal/pkg/webhooks/repository"
+	hookusec "forum/internal/pkg/webhooks/usecase"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	dbConnStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", "ekasy", "ekasy", "127.0.0.1", "5432", "forum")
-	db, err := db.NewDatabase(dbConnStr)
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the effective config with secrets masked and exit")
+	loader := config.NewLoader(fs)
+	fs.Parse(os.Args[1:])
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Default().Fatalf("config error: %v", err)
+	}
+
+	if *printConfig {
+		fmt.Println(cfg.String())
+		return
+	}
+	if len(fs.Args()) == 0 {
+		if err = cfg.ValidateServe(); err != nil {
+			log.Default().Fatalf("config error: %v", err)
+		}
+	}
+
+	// the standard log package is left to report failures, as JSON too
+	lg := logger.New(os.Stderr, cfg.LogLevel)
+	log.SetFlags(0)
+	log.SetOutput(lg.Writer(logger.LevelError))
+
+	conn, err := db.NewDatabase(cfg.Database)
 	if err != nil {
+		log.Default().Fatalf("database: %v", err)
+	}
+	defer conn.Close()
+
+	if args := fs.Args(); len(args) != 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(context.Background(), conn, args[1:])
+		case "reconcile":
+			err = runReconcile(context.Background(), conn, lg)
+		default:
+			err = fmt.Errorf("unknown command %q", args[0])
+		}
+		if err != nil {
+			log.Default().Fatalf("%s: %v", args[0], err)
+		}
 		return
 	}
-	defer db.Close()
 
-	ur := userrepo.NewUserRepository(db)
-	uu := userusec.NewUserUsecase(ur)
+	cursors := cursor.NewCodec(cfg.Server.CursorSecret)
+	if cfg.Server.CursorSecret == "" {
+		lg.Warn(context.Background(), "cursor secret is not set, pagination cursors will not survive a restart")
+		cursors, err = cursor.NewRandomCodec()
+		if err != nil {
+			log.Default().Fatalf("cursor secret: %v", err)
+		}
+	}
+
+	kr := toknrepo.NewTokenRepository(conn, lg)
+	ku := toknusec.NewTokenUsecase(kr)
+	kd := tokndeli.NewTokenDelivery(ku)
+
+	ur := userrepo.NewUserRepository(conn, lg)
+	uu := userusec.NewUserUsecase(ur, ku)
 	ud := userdeli.NewUserDelivery(uu)
 
-	fr := forumrepo.NewForumRepository(db)
+	mr := modrepo.NewModeratorRepository(conn, lg)
+	mu := modusec.NewModeratorUsecase(mr)
+	md := moddeli.NewModeratorDelivery(mu)
+
+	br := banrepo.NewBanRepository(conn, lg)
+	bu := banusec.NewBanUsecase(br, mu)
+	bd := bandeli.NewBanDelivery(bu)
+
+	lr := fltrrepo.NewFilterRepository(conn, lg)
+	lu := fltrusec.NewFilterUsecase(lr, mu, cfg.Server.BannedWordList())
+
+	fr := forumrepo.NewForumRepository(conn, lg)
 	fu := forumusec.NewForumUsecase(fr)
-	fd := forumdeli.NewForumDelivery(fu)
+	fd := forumdeli.NewForumDelivery(fu, cursors)
 
-	tr := thrdrepo.NewThreadRepository(db)
-	tu := thrdusec.NewThreadUsecase(tr)
-	td := thrddeli.NewForumDelivery(tu)
+	tr := thrdrepo.NewThreadRepository(conn, lg)
+	tu := thrdusec.NewThreadUsecase(tr, mu, bu, lu)
+	td := thrddeli.NewForumDelivery(tu, cursors)
 
-	pr := postrepo.NewPostRepository(db)
-	pu := postusec.NewPostUsecase(pr)
-	pd := postdeli.NewPostDelivery(pu)
+	pr := postrepo.NewPostRepository(conn, lg)
+	pu := postusec.NewPostUsecase(pr, mu, bu, lu)
+	pd := postdeli.NewPostDelivery(pu, cursors)
 
-	vr := voterepo.NewVoteRepository(db)
-	vu := voteusec.NewVoteUsecase(vr)
+	ld := fltrdeli.NewFilterDelivery(lu, pu, tu)
+
+	vr := voterepo.NewVoteRepository(conn, lg)
+	vu := voteusec.NewVoteUsecase(vr, bu)
 	vd := votedeli.NewVoteDelivery(vu)
 
-	sr := srvcrepo.NewServiceRepository(db)
-	su := srvcusec.NewServiceUsecase(sr)
+	rr := rprtrepo.NewReportRepository(conn, lg)
+	ru := rprtusec.NewReportUsecase(rr, mu)
+	rd := rprtdeli.NewReportDelivery(ru)
+
+	nr := ntfyrepo.NewNotificationRepository(conn, lg)
+	nu := ntfyusec.NewNotificationUsecase(nr)
+	nd := ntfydeli.NewNotificationDelivery(nu)
+
+	er := evntrepo.NewEventRepository(conn, lg)
+	eventHub, err := evnthub.NewHub(cfg.Database.DSN(), er, cfg.Server.EventRetention.Duration(), lg)
+	if err != nil {
+		log.Default().Fatalf("event hub: %v", err)
+	}
+	eu := evntusec.NewEventUsecase(er, eventHub)
+	ed := evntdeli.NewEventDelivery(eu, cfg.Database.QueryTimeout.Duration(), cfg.Server.AllowedOriginList(), lg)
+
+	hr := hookrepo.NewWebhookRepository(conn, lg)
+	hu := hookusec.NewWebhookUsecase(hr)
+	hd := hookdeli.NewWebhookDelivery(hu)
+	dispatcher := hookdisp.NewDispatcher(hr, nil, lg)
+
+	schr := srchrepo.NewSearchRepository(conn, lg)
+	schu := srchusec.NewSearchUsecase(schr)
+	schd := srchdeli.NewSearchDelivery(schu)
+
+	sr := srvcrepo.NewServiceRepository(conn, lg)
+	su := srvcusec.NewServiceUsecase(sr, cfg.Server.ClearMode, cursors)
 	sd := srvcdeli.NewServiceDelivery(su)
 
-	r := mux.NewRouter()
-	r = r.PathPrefix("/api").Subrouter()
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		log.Default().Fatalf("migrations: %v", err)
+	}
+	hcr := hlthrepo.NewHealthRepository(conn)
+	hcu := hlthusec.NewHealthUsecase(hcr, migrator, cfg.Server.ReadyTimeout.Duration(), lg)
+	hcd := hlthdeli.NewHealthDelivery(hcu)
+
+	metrics.Default.Register(metrics.DBStats(conn))
+	root := mux.NewRouter()
+	root.Handle("/metrics", metrics.Default.Handler()).Methods(http.MethodGet)
+	hcd.Routing(root)
+
+	r := root.PathPrefix("/api").Subrouter()
+	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.AccessLogMiddleware(lg))
+	r.Use(middleware.MetricsMiddleware)
+	r.Use(middleware.StreamDeadlineMiddleware)
 	r.Use(middleware.ContentTypeMiddleware)
+	r.Use(middleware.QueryDeadlineMiddleware(cfg.Database.QueryTimeout.Duration()))
+	r.Use(middleware.AdminTokenMiddleware(cfg.Server.AdminToken))
+	r.Use(middleware.BearerTokenMiddleware(ku, !cfg.Server.AuthDisabled))
 	ud.Routing(r)
+	kd.Routing(r)
 	fd.Routing(r)
+	md.Routing(r)
+	bd.Routing(r)
 	td.Routing(r)
 	pd.Routing(r)
 	vd.Routing(r)
+	nd.Routing(r)
+	rd.Routing(r)
+	ld.Routing(r)
+	ed.Routing(r)
+	hd.Routing(r)
+	schd.Routing(r)
 	sd.Routing(r)
 
-	port := 5000
-	log.Default().Printf("start serving ::%d\n", port)
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), r)
-	log.Default().Fatalf("http serve error %v", err)
+	srv := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      root,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration(),
+		WriteTimeout: cfg.Server.WriteTimeout.Duration(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration(),
+		ConnContext:  middleware.ConnContext,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// the hub ends every open stream once ctx is done, so they do not hold
+	// up the drain
+	go eventHub.Run(ctx)
+	go dispatcher.Run(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		lg.Info(context.Background(), "start serving", "addr", cfg.Server.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+		log.Default().Fatalf("http serve error %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	lg.Info(context.Background(), "shutting down", "drain", cfg.Server.ShutdownTimeout.Duration())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration())
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		lg.Warn(context.Background(), "drain timeout exceeded, closing remaining connections")
+		err = srv.Close()
+	}
+	if err != nil {
+		log.Default().Printf("http shutdown error %v\n", err)
+	}
 }
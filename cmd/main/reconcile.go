@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"forum/internal/config"
+	"forum/internal/pkg/logger"
+	srvcrepo "forum/internal/pkg/service/repository"
+	srvcusec "forum/internal/pkg/service/usecase"
+	"os"
+	"text/tabwriter"
+)
+
+// runReconcile repairs drift of the counters behind /service/status and
+// prints what changed.
+func runReconcile(ctx context.Context, conn *sql.DB, lg *logger.Logger) error {
+	su := srvcusec.NewServiceUsecase(srvcrepo.NewServiceRepository(conn, lg), config.ClearDisabled, nil)
+	before, after, err := su.ReconcileCounters(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COUNTER\tRECORDED\tCOUNTED")
+	fmt.Fprintf(tw, "users\t%d\t%d\n", before.User, after.User)
+	fmt.Fprintf(tw, "forum\t%d\t%d\n", before.Forum, after.Forum)
+	fmt.Fprintf(tw, "threads\t%d\t%d\n", before.Thread, after.Thread)
+	fmt.Fprintf(tw, "posts\t%d\t%d\n", before.Post, after.Post)
+	return tw.Flush()
+}
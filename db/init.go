@@ -1,29 +1,31 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"log"
-	"time"
+	"fmt"
+	"forum/internal/config"
 
 	_ "github.com/jackc/pgx/stdlib"
 )
 
-func NewDatabase(connString string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", connString)
+func NewDatabase(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.DSN())
 	if err != nil {
-		log.Fatalf("could not connect to database: %s", err.Error())
-		return nil, err
+		return nil, fmt.Errorf("could not connect to database: %w", err)
 	}
 
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout.Duration())
+	defer cancel()
+	err = db.PingContext(ctx)
 	if err != nil {
-		log.Fatalf("error with ping: %s", err.Error())
-		return nil, err
+		db.Close()
+		return nil, fmt.Errorf("error with ping: %w", err)
 	}
 
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(100)
-	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration())
 
 	return db, nil
 }
@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the pg_advisory_lock key that keeps two instances
+// from migrating the same database at once.
+const migrationLockKey int64 = 7_450_113_201
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+	logger     *log.Logger
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     log.Default(),
+	}, nil
+}
+
+// LatestVersion is the version the embedded migrations bring a database to.
+func (m *Migrator) LatestVersion() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion is the highest applied version, zero for a fresh database.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int64, error) {
+	var version int64
+	row := m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`)
+	err := row.Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Up applies every pending migration, each in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	applied := make([]*Migration, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+			m.logger.Printf("applying migration %04d_%s\n", mig.Version, mig.Name)
+			err = runInTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last applied migration. It returns nil when there is
+// nothing to revert.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil || current == 0 {
+			return err
+		}
+
+		mig := m.find(current)
+		if mig == nil {
+			return fmt.Errorf("applied migration %04d is unknown to this binary", current)
+		}
+		m.logger.Printf("reverting migration %04d_%s\n", mig.Version, mig.Name)
+		err = runInTx(ctx, conn, mig.Down,
+			`DELETE FROM schema_migrations WHERE version = $1;`, mig.Version)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		reverted = mig
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration together with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	if err := ensureMigrationsTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := &MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a dedicated connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockKey)
+		if unlockErr != nil {
+			m.logger.Printf("release migration lock: %s\n", unlockErr.Error())
+		}
+	}()
+
+	if err = ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func ensureMigrationsTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version     BIGINT                      NOT NULL PRIMARY KEY,
+			name        TEXT                        NOT NULL,
+			applied_at  TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT now()
+		);`)
+	return err
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version int64
+	row := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`)
+	err := row.Scan(&version)
+	return version, err
+}
+
+// runInTx executes a migration script and its bookkeeping statement
+// atomically.
+func runInTx(ctx context.Context, conn *sql.Conn, script string, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	// without arguments pgx uses the simple protocol, which accepts a
+	// script of several statements
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs.
+func loadMigrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		sep := strings.Index(base, "_")
+		if sep <= 0 {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name", fileName)
+		}
+		version, err := strconv.ParseInt(base[:sep], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", fileName, err)
+		}
+
+		buf, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: base[sep+1:]}
+			byVersion[version] = mig
+		}
+		if direction == "up" {
+			mig.Up = string(buf)
+		} else {
+			mig.Down = string(buf)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: both up and down scripts are required", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
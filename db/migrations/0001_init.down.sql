@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS forum_users CASCADE;
+DROP TABLE IF EXISTS votes CASCADE;
+DROP TABLE IF EXISTS posts CASCADE;
+DROP TABLE IF EXISTS threads CASCADE;
+DROP TABLE IF EXISTS forum CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+
+DROP FUNCTION IF EXISTS vote_insert();
+DROP FUNCTION IF EXISTS vote_update();
+DROP FUNCTION IF EXISTS increment_posts_count();
+DROP FUNCTION IF EXISTS increment_threads_count();
+DROP FUNCTION IF EXISTS post_paste_forum_user();
+DROP FUNCTION IF EXISTS thread_paste_forum_user();
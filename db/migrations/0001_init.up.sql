@@ -1,18 +1,11 @@
+-- исходная схема форума; все операторы идемпотентны, поэтому миграция
+-- безопасно применяется и к базе, созданной старым db/init.sql
 CREATE EXTENSION IF NOT EXISTS citext;
 
 
 -----------------------
 ----- БЛОК ТАБЛИЦ -----
 -----------------------
--- очистка таблиц
-DROP TABLE IF EXISTS users CASCADE;
-DROP TABLE IF EXISTS forum CASCADE;
-DROP TABLE IF EXISTS threads CASCADE;
-DROP TABLE IF EXISTS posts CASCADE;
-DROP TABLE IF EXISTS votes CASCADE;
-DROP TABLE IF EXISTS forum_users CASCADE;
-
-
 CREATE TABLE IF NOT EXISTS users (
     nickname    CITEXT COLLATE "C"  NOT NULL PRIMARY KEY,
     fullname    TEXT                NOT NULL,
@@ -86,20 +79,15 @@ CREATE TABLE IF NOT EXISTS forum_users (
 -- индексы для forum
 
 -- индексы для threads
-DROP INDEX IF EXISTS index_threads_slug;
 CREATE UNIQUE INDEX IF NOT EXISTS index_threads_slug ON threads(slug) WHERE TRIM(slug) <> '';
 
-DROP INDEX IF EXISTS index_thread__forum_created;
 CREATE INDEX IF NOT EXISTS index_thread__forum_created ON threads(forum, created); -- для сортировки
 
-DROP INDEX IF EXISTS index_thread__slug_id_forum;
 CREATE INDEX IF NOT EXISTS index_thread__slug_id_forum ON threads(slug, id, forum); -- + ~400rps
 
 -- индексы для posts
-DROP INDEX IF EXISTS index_posts__thread;
 CREATE INDEX IF NOT EXISTS index_posts__thread ON posts(thread);
 
-DROP INDEX IF EXISTS index_posts__id_thread;
 CREATE INDEX IF NOT EXISTS index_posts__id_thread ON posts(id, thread);
 
 -- индексы для forum_users
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS decrement_posts_count ON posts;
+DROP FUNCTION IF EXISTS decrement_posts_count();
+
+DROP TRIGGER IF EXISTS tombstone_posts_count ON posts;
+DROP FUNCTION IF EXISTS tombstone_posts_count();
+
+ALTER TABLE posts DROP COLUMN IF EXISTS isDeleted;
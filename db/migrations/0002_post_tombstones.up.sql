@@ -0,0 +1,38 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS isDeleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- удаление поста (надгробие) -> декремент числа постов в форуме
+CREATE OR REPLACE FUNCTION tombstone_posts_count() RETURNS TRIGGER AS $tombstone_posts_count$
+BEGIN
+    IF OLD.isDeleted = NEW.isDeleted
+        THEN RETURN NULL;
+    END IF;
+    UPDATE forum SET
+        posts = posts + CASE WHEN NEW.isDeleted THEN -1 ELSE 1 END
+    WHERE slug = NEW.forum;
+
+    RETURN NULL;
+END;
+$tombstone_posts_count$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tombstone_posts_count ON posts;
+CREATE TRIGGER tombstone_posts_count AFTER UPDATE OF isDeleted ON posts FOR EACH ROW EXECUTE PROCEDURE tombstone_posts_count();
+
+
+-- физическое удаление поста -> декремент числа постов в форуме,
+-- если пост ещё не был учтён как удалённый
+CREATE OR REPLACE FUNCTION decrement_posts_count() RETURNS TRIGGER AS $decrement_posts_count$
+BEGIN
+    IF OLD.isDeleted
+        THEN RETURN NULL;
+    END IF;
+    UPDATE forum SET
+        posts = (posts - 1)
+    WHERE slug = OLD.forum;
+
+    RETURN NULL;
+END;
+$decrement_posts_count$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS decrement_posts_count ON posts;
+CREATE TRIGGER decrement_posts_count AFTER DELETE ON posts FOR EACH ROW EXECUTE PROCEDURE decrement_posts_count();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS post_revision ON posts;
+DROP FUNCTION IF EXISTS post_revision();
+
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,38 @@
+-- история правок постов; исходный текст сохраняется ревизией 1 при первой
+-- правке, поэтому посты без правок не занимают места в таблице
+CREATE TABLE IF NOT EXISTS post_revisions (
+    post        BIGINT                      NOT NULL,
+    rev         INTEGER                     NOT NULL,
+    message     TEXT                        NOT NULL,
+    editor      CITEXT                      NOT NULL,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (post) REFERENCES posts (id) ON DELETE CASCADE,
+    PRIMARY KEY (post, rev)
+);
+
+
+-- изменение текста поста -> новая ревизия; автор правки берётся из
+-- forum.editor текущей транзакции, иначе правка приписывается автору поста
+CREATE OR REPLACE FUNCTION post_revision() RETURNS TRIGGER AS $post_revision$
+DECLARE
+    last_rev INTEGER;
+BEGIN
+    SELECT MAX(rev) INTO last_rev FROM post_revisions WHERE post = NEW.id;
+    IF last_rev IS NULL THEN
+        INSERT INTO post_revisions (post, rev, message, editor, created)
+        VALUES (OLD.id, 1, OLD.message, OLD.author, OLD.created);
+        last_rev := 1;
+    END IF;
+
+    INSERT INTO post_revisions (post, rev, message, editor)
+    VALUES (NEW.id, last_rev + 1, NEW.message,
+            COALESCE(NULLIF(current_setting('forum.editor', TRUE), ''), NEW.author));
+
+    RETURN NULL;
+END;
+$post_revision$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_revision ON posts;
+CREATE TRIGGER post_revision AFTER UPDATE OF message ON posts
+    FOR EACH ROW WHEN (OLD.message IS DISTINCT FROM NEW.message)
+    EXECUTE PROCEDURE post_revision();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS decrement_threads_count ON threads;
+DROP FUNCTION IF EXISTS decrement_threads_count();
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_status_check;
+ALTER TABLE threads DROP COLUMN IF EXISTS status;
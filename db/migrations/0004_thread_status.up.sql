@@ -0,0 +1,20 @@
+-- статус треда: в закрытый и архивный тред нельзя писать посты и голосовать
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_status_check;
+ALTER TABLE threads ADD CONSTRAINT threads_status_check CHECK (status IN ('open', 'closed', 'archived'));
+
+
+-- удаление трэда -> декремент числа трэдов в форуме
+CREATE OR REPLACE FUNCTION decrement_threads_count() RETURNS TRIGGER AS $decrement_threads_count$
+BEGIN
+    UPDATE forum SET
+        threads = (threads - 1)
+    WHERE slug = OLD.forum;
+
+    RETURN NULL;
+END;
+$decrement_threads_count$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS decrement_threads_count ON threads;
+CREATE TRIGGER decrement_threads_count AFTER DELETE ON threads FOR EACH ROW EXECUTE PROCEDURE decrement_threads_count();
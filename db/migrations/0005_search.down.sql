@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS index_threads__search_tsv;
+DROP INDEX IF EXISTS index_posts__message_tsv;
+
+ALTER TABLE threads DROP COLUMN IF EXISTS search_tsv;
+ALTER TABLE posts DROP COLUMN IF EXISTS message_tsv;
@@ -0,0 +1,14 @@
+-- полнотекстовый поиск; конфигурация 'simple' не стеммит слова, поэтому
+-- одинаково ведёт себя на любом языке постов
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS message_tsv TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS search_tsv TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', message), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS index_posts__message_tsv ON posts USING GIN (message_tsv);
+
+CREATE INDEX IF NOT EXISTS index_threads__search_tsv ON threads USING GIN (search_tsv);
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS thread_votes_event ON threads;
+DROP FUNCTION IF EXISTS thread_votes_event();
+
+DROP TRIGGER IF EXISTS post_edit_event ON posts;
+DROP FUNCTION IF EXISTS post_edit_event();
+
+DROP TRIGGER IF EXISTS post_insert_event ON posts;
+DROP FUNCTION IF EXISTS post_insert_event();
+
+DROP FUNCTION IF EXISTS emit_thread_event(INTEGER, TEXT, JSON);
+
+DROP TABLE IF EXISTS thread_events;
@@ -0,0 +1,76 @@
+-- журнал событий тредов для SSE: новые посты, правки постов и изменения
+-- голосов; id события служит Last-Event-ID, об id сообщает NOTIFY
+CREATE TABLE IF NOT EXISTS thread_events (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    thread      INTEGER                     NOT NULL,
+    kind        TEXT                        NOT NULL,
+    payload     JSON                        NOT NULL,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (thread) REFERENCES threads (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_thread_events__thread_id ON thread_events(thread, id);
+
+CREATE INDEX IF NOT EXISTS index_thread_events__created ON thread_events(created); -- для очистки
+
+
+CREATE OR REPLACE FUNCTION emit_thread_event(thread_id INTEGER, event_kind TEXT, event_payload JSON) RETURNS VOID AS $emit_thread_event$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO thread_events (thread, kind, payload)
+    VALUES (thread_id, event_kind, event_payload)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('thread_events', json_build_object('id', event_id, 'thread', thread_id)::text);
+END;
+$emit_thread_event$ LANGUAGE plpgsql;
+
+
+-- создание поста -> событие post
+CREATE OR REPLACE FUNCTION post_insert_event() RETURNS TRIGGER AS $post_insert_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, 'post', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_insert_event$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_insert_event ON posts;
+CREATE TRIGGER post_insert_event AFTER INSERT ON posts FOR EACH ROW EXECUTE PROCEDURE post_insert_event();
+
+
+-- правка или удаление поста -> событие post_edit
+CREATE OR REPLACE FUNCTION post_edit_event() RETURNS TRIGGER AS $post_edit_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, 'post_edit', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_edit_event$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_edit_event ON posts;
+CREATE TRIGGER post_edit_event AFTER UPDATE OF message, isDeleted ON posts
+    FOR EACH ROW WHEN (OLD.message IS DISTINCT FROM NEW.message OR OLD.isDeleted IS DISTINCT FROM NEW.isDeleted)
+    EXECUTE PROCEDURE post_edit_event();
+
+
+-- изменение голосов треда (vote_insert, vote_update) -> событие votes
+CREATE OR REPLACE FUNCTION thread_votes_event() RETURNS TRIGGER AS $thread_votes_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.id, 'votes', json_build_object('thread', NEW.id, 'votes', NEW.votes));
+
+    RETURN NULL;
+END;
+$thread_votes_event$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS thread_votes_event ON threads;
+CREATE TRIGGER thread_votes_event AFTER UPDATE OF votes ON threads
+    FOR EACH ROW WHEN (OLD.votes IS DISTINCT FROM NEW.votes)
+    EXECUTE PROCEDURE thread_votes_event();
@@ -0,0 +1,50 @@
+DROP TRIGGER IF EXISTS thread_insert_event ON threads;
+DROP FUNCTION IF EXISTS thread_insert_event();
+
+
+DROP FUNCTION IF EXISTS emit_thread_event(INTEGER, CITEXT, TEXT, JSON);
+
+CREATE OR REPLACE FUNCTION emit_thread_event(thread_id INTEGER, event_kind TEXT, event_payload JSON) RETURNS VOID AS $emit_thread_event$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO thread_events (thread, kind, payload)
+    VALUES (thread_id, event_kind, event_payload)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('thread_events', json_build_object('id', event_id, 'thread', thread_id)::text);
+END;
+$emit_thread_event$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION post_insert_event() RETURNS TRIGGER AS $post_insert_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, 'post', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_insert_event$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION post_edit_event() RETURNS TRIGGER AS $post_edit_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, 'post_edit', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_edit_event$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION thread_votes_event() RETURNS TRIGGER AS $thread_votes_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.id, 'votes', json_build_object('thread', NEW.id, 'votes', NEW.votes));
+
+    RETURN NULL;
+END;
+$thread_votes_event$ LANGUAGE plpgsql;
+
+
+ALTER TABLE thread_events DROP COLUMN IF EXISTS forum;
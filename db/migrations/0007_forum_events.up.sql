@@ -0,0 +1,72 @@
+-- события получают форум, чтобы на них можно было подписаться по форуму,
+-- и новое событие thread о создании треда
+ALTER TABLE thread_events ADD COLUMN IF NOT EXISTS forum CITEXT NOT NULL DEFAULT '';
+
+UPDATE thread_events e SET forum = t.forum FROM threads t WHERE t.id = e.thread AND e.forum = '';
+
+
+DROP FUNCTION IF EXISTS emit_thread_event(INTEGER, TEXT, JSON);
+
+CREATE OR REPLACE FUNCTION emit_thread_event(thread_id INTEGER, forum_slug CITEXT, event_kind TEXT, event_payload JSON) RETURNS VOID AS $emit_thread_event$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO thread_events (thread, forum, kind, payload)
+    VALUES (thread_id, forum_slug, event_kind, event_payload)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('thread_events', json_build_object('id', event_id, 'thread', thread_id, 'forum', forum_slug)::text);
+END;
+$emit_thread_event$ LANGUAGE plpgsql;
+
+
+-- создание поста -> событие post
+CREATE OR REPLACE FUNCTION post_insert_event() RETURNS TRIGGER AS $post_insert_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, NEW.forum, 'post', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_insert_event$ LANGUAGE plpgsql;
+
+
+-- правка или удаление поста -> событие post_edit
+CREATE OR REPLACE FUNCTION post_edit_event() RETURNS TRIGGER AS $post_edit_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.thread, NEW.forum, 'post_edit', json_build_object(
+        'id', NEW.id, 'parent', NEW.parent, 'author', NEW.author, 'message', NEW.message,
+        'isEdited', NEW.isEdited, 'isDeleted', NEW.isDeleted, 'forum', NEW.forum,
+        'thread', NEW.thread, 'created', NEW.created));
+
+    RETURN NULL;
+END;
+$post_edit_event$ LANGUAGE plpgsql;
+
+
+-- изменение голосов треда -> событие votes
+CREATE OR REPLACE FUNCTION thread_votes_event() RETURNS TRIGGER AS $thread_votes_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.id, NEW.forum, 'votes', json_build_object('thread', NEW.id, 'votes', NEW.votes));
+
+    RETURN NULL;
+END;
+$thread_votes_event$ LANGUAGE plpgsql;
+
+
+-- создание трэда -> событие thread
+CREATE OR REPLACE FUNCTION thread_insert_event() RETURNS TRIGGER AS $thread_insert_event$
+BEGIN
+    PERFORM emit_thread_event(NEW.id, NEW.forum, 'thread', json_build_object(
+        'id', NEW.id, 'title', NEW.title, 'author', NEW.author, 'forum', NEW.forum,
+        'message', NEW.message, 'votes', NEW.votes, 'slug', NEW.slug,
+        'created', NEW.created, 'status', NEW.status));
+
+    RETURN NULL;
+END;
+$thread_insert_event$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS thread_insert_event ON threads;
+CREATE TRIGGER thread_insert_event AFTER INSERT ON threads FOR EACH ROW EXECUTE PROCEDURE thread_insert_event();
@@ -0,0 +1,15 @@
+CREATE OR REPLACE FUNCTION emit_thread_event(thread_id INTEGER, forum_slug CITEXT, event_kind TEXT, event_payload JSON) RETURNS VOID AS $emit_thread_event$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO thread_events (thread, forum, kind, payload)
+    VALUES (thread_id, forum_slug, event_kind, event_payload)
+    RETURNING id INTO event_id;
+
+    PERFORM pg_notify('thread_events', json_build_object('id', event_id, 'thread', thread_id, 'forum', forum_slug)::text);
+END;
+$emit_thread_event$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_outbox;
+DROP TABLE IF EXISTS webhooks;
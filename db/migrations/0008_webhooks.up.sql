@@ -0,0 +1,67 @@
+-- вебхуки форумов: события попадают в outbox в той же транзакции, что и
+-- вставка треда, поста или голоса; рассылкой занимается диспетчер сервиса
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          SERIAL                      NOT NULL PRIMARY KEY,
+    forum       CITEXT                      NOT NULL,
+    url         TEXT                        NOT NULL,
+    secret      TEXT                        NOT NULL,
+    events      TEXT                        ARRAY NOT NULL DEFAULT '{}', -- пустой массив = все события
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_webhooks__forum ON webhooks(forum);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id              BIGSERIAL                   NOT NULL PRIMARY KEY,
+    webhook         INTEGER                     NOT NULL,
+    forum           CITEXT                      NOT NULL,
+    thread          INTEGER                     NOT NULL,
+    kind            TEXT                        NOT NULL,
+    payload         JSON                        NOT NULL,
+    status          TEXT                        NOT NULL DEFAULT 'pending',
+    attempts        INTEGER                     NOT NULL DEFAULT 0,
+    next_attempt    TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    created         TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (webhook) REFERENCES webhooks (id) ON DELETE CASCADE,
+    CONSTRAINT webhook_outbox_status_check CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_outbox__pending ON webhook_outbox(next_attempt) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS index_webhook_outbox__webhook ON webhook_outbox(webhook, status);
+
+-- журнал попыток доставки
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    outbox      BIGINT                      NOT NULL,
+    webhook     INTEGER                     NOT NULL,
+    attempt     INTEGER                     NOT NULL,
+    status_code INTEGER                     NOT NULL DEFAULT 0,
+    error       TEXT                        NOT NULL DEFAULT '',
+    duration_ms BIGINT                      NOT NULL DEFAULT 0,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (outbox) REFERENCES webhook_outbox (id) ON DELETE CASCADE,
+    FOREIGN KEY (webhook) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_deliveries__webhook_id ON webhook_deliveries(webhook, id);
+
+
+-- событие треда -> запись в outbox для каждого подходящего вебхука форума
+CREATE OR REPLACE FUNCTION emit_thread_event(thread_id INTEGER, forum_slug CITEXT, event_kind TEXT, event_payload JSON) RETURNS VOID AS $emit_thread_event$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO thread_events (thread, forum, kind, payload)
+    VALUES (thread_id, forum_slug, event_kind, event_payload)
+    RETURNING id INTO event_id;
+
+    INSERT INTO webhook_outbox (webhook, forum, thread, kind, payload)
+    SELECT w.id, forum_slug, thread_id, event_kind, event_payload
+    FROM webhooks w
+    WHERE w.forum = forum_slug AND (cardinality(w.events) = 0 OR event_kind = ANY(w.events));
+
+    PERFORM pg_notify('thread_events', json_build_object('id', event_id, 'thread', thread_id, 'forum', forum_slug)::text);
+END;
+$emit_thread_event$ LANGUAGE plpgsql;
@@ -0,0 +1,30 @@
+-- подписки на треды и уведомления пользователей; уведомления создаются
+-- вместе с постами в PostRepository.CreatePosts
+CREATE TABLE IF NOT EXISTS thread_subscriptions (
+    thread      INTEGER                     NOT NULL,
+    nickname    CITEXT                      NOT NULL,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (thread) REFERENCES threads (id) ON DELETE CASCADE,
+    FOREIGN KEY (nickname) REFERENCES users (nickname),
+    PRIMARY KEY (thread, nickname)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    nickname    CITEXT                      NOT NULL,
+    kind        TEXT                        NOT NULL, -- reply | thread
+    post        BIGINT                      NOT NULL,
+    thread      INTEGER                     NOT NULL,
+    author      CITEXT                      NOT NULL,
+    isRead      BOOLEAN                     NOT NULL DEFAULT FALSE,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (nickname) REFERENCES users (nickname),
+    FOREIGN KEY (post) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (thread) REFERENCES threads (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_notifications__nickname_id ON notifications(nickname, id);
+
+CREATE INDEX IF NOT EXISTS index_notifications__unread ON notifications(nickname) WHERE NOT isRead; -- для счётчика
+
+CREATE INDEX IF NOT EXISTS index_notifications__post ON notifications(post); -- для каскадного удаления
@@ -0,0 +1,2 @@
+DELETE FROM notifications WHERE kind = 'mention';
+DROP TABLE IF EXISTS post_mentions;
@@ -0,0 +1,11 @@
+-- упоминания @nickname в постах; разбираются в usecase постов, здесь
+-- хранятся только существующие пользователи
+CREATE TABLE IF NOT EXISTS post_mentions (
+    post        BIGINT  NOT NULL,
+    nickname    CITEXT  NOT NULL,
+    FOREIGN KEY (post) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (nickname) REFERENCES users (nickname),
+    PRIMARY KEY (post, nickname)
+);
+
+CREATE INDEX IF NOT EXISTS index_post_mentions__nickname_post ON post_mentions(nickname, post); -- для /user/{nickname}/mentions
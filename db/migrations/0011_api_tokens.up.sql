@@ -0,0 +1,11 @@
+-- персональные API-токены; хранится только sha256 от токена
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    nickname    CITEXT                      NOT NULL,
+    name        TEXT                        NOT NULL DEFAULT '',
+    hash        BYTEA                       NOT NULL UNIQUE,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (nickname) REFERENCES users (nickname)
+);
+
+CREATE INDEX IF NOT EXISTS index_api_tokens__nickname ON api_tokens(nickname);
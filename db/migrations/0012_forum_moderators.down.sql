@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS index_threads__forum_pinned;
+ALTER TABLE threads DROP COLUMN IF EXISTS pinned;
+DROP TABLE IF EXISTS forum_moderators;
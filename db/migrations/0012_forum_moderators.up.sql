@@ -0,0 +1,16 @@
+-- модераторы форумов; владельцем форума считается forum.author, админ
+-- сайта определяется токеном X-Admin-Token
+CREATE TABLE IF NOT EXISTS forum_moderators (
+    forum       CITEXT                      NOT NULL,
+    nickname    CITEXT                      NOT NULL,
+    appointedBy CITEXT                      NOT NULL DEFAULT '',
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE,
+    FOREIGN KEY (nickname) REFERENCES users (nickname),
+    PRIMARY KEY (forum, nickname)
+);
+
+-- закреплённые модераторами треды
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS index_threads__forum_pinned ON threads(forum) WHERE pinned;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS suspendReason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspendedUntil;
+ALTER TABLE users DROP COLUMN IF EXISTS isSuspended;
+DROP TABLE IF EXISTS forum_bans;
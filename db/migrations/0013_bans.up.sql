@@ -0,0 +1,17 @@
+-- баны пользователей в форумах и блокировка на всём сайте; истёкшие баны
+-- просто перестают действовать
+CREATE TABLE IF NOT EXISTS forum_bans (
+    forum       CITEXT                      NOT NULL,
+    nickname    CITEXT                      NOT NULL,
+    reason      TEXT                        NOT NULL DEFAULT '',
+    moderator   CITEXT                      NOT NULL DEFAULT '',
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    expires     TIMESTAMP WITH TIME ZONE,   -- NULL: бессрочно
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE,
+    FOREIGN KEY (nickname) REFERENCES users (nickname),
+    PRIMARY KEY (forum, nickname)
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS isSuspended BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspendedUntil TIMESTAMP WITH TIME ZONE; -- NULL: бессрочно
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspendReason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,27 @@
+-- жалобы на посты и треды и очередь модерации форума
+CREATE TABLE IF NOT EXISTS reports (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    kind        TEXT                        NOT NULL, -- post | thread
+    post        BIGINT,
+    thread      INTEGER                     NOT NULL,
+    forum       CITEXT                      NOT NULL,
+    reporter    CITEXT                      NOT NULL,
+    category    TEXT                        NOT NULL,
+    comment     TEXT                        NOT NULL DEFAULT '',
+    status      TEXT                        NOT NULL DEFAULT 'open',
+    resolver    CITEXT                      NOT NULL DEFAULT '',
+    resolved    TIMESTAMP WITH TIME ZONE,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    CONSTRAINT reports_category_check CHECK (category IN ('spam', 'abuse', 'off_topic', 'other')),
+    CONSTRAINT reports_status_check CHECK (status IN ('open', 'resolved', 'dismissed')),
+    FOREIGN KEY (post) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (thread) REFERENCES threads (id) ON DELETE CASCADE,
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE,
+    FOREIGN KEY (reporter) REFERENCES users (nickname)
+);
+
+CREATE INDEX IF NOT EXISTS index_reports__forum_id ON reports(forum, id);
+
+CREATE INDEX IF NOT EXISTS index_reports__post ON reports(post) WHERE post IS NOT NULL; -- для каскадного удаления
+
+CREATE INDEX IF NOT EXISTS index_reports__thread ON reports(thread);
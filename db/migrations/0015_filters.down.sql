@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS held_content;
+DROP TABLE IF EXISTS forum_filters;
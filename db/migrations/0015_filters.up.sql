@@ -0,0 +1,32 @@
+-- настройки фильтров контента форума; форум без строки живёт с настройками
+-- по умолчанию
+CREATE TABLE IF NOT EXISTS forum_filters (
+    forum               CITEXT      NOT NULL PRIMARY KEY,
+    bannedWords         TEXT[]      NOT NULL DEFAULT '{}', -- в дополнение к общему списку
+    allowedWords        TEXT[]      NOT NULL DEFAULT '{}', -- исключения из общего списка
+    bannedWordsAction   TEXT        NOT NULL DEFAULT 'mask',
+    maxLength           INTEGER     NOT NULL DEFAULT 0,    -- 0: без ограничения
+    maxLengthAction     TEXT        NOT NULL DEFAULT 'reject',
+    maxLinks            INTEGER     NOT NULL DEFAULT 0,
+    maxLinksAction      TEXT        NOT NULL DEFAULT 'reject',
+    shouting            BOOLEAN     NOT NULL DEFAULT FALSE,
+    shoutingAction      TEXT        NOT NULL DEFAULT 'mask',
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE
+);
+
+-- задержанные фильтром посты и треды ждут решения модератора; payload
+-- хранит исходный запрос
+CREATE TABLE IF NOT EXISTS held_content (
+    id          BIGSERIAL                   NOT NULL PRIMARY KEY,
+    kind        TEXT                        NOT NULL, -- post | thread
+    forum       CITEXT                      NOT NULL,
+    thread      INTEGER,
+    author      CITEXT                      NOT NULL,
+    rule        TEXT                        NOT NULL,
+    payload     JSONB                       NOT NULL,
+    created     TIMESTAMP WITH TIME ZONE    NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (forum) REFERENCES forum (slug) ON DELETE CASCADE,
+    FOREIGN KEY (thread) REFERENCES threads (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS index_held_content__forum_id ON held_content(forum, id);
@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS count_inserted_rows ON users;
+DROP TRIGGER IF EXISTS count_deleted_rows ON users;
+DROP TRIGGER IF EXISTS count_truncated_rows ON users;
+DROP TRIGGER IF EXISTS count_inserted_rows ON forum;
+DROP TRIGGER IF EXISTS count_deleted_rows ON forum;
+DROP TRIGGER IF EXISTS count_truncated_rows ON forum;
+DROP TRIGGER IF EXISTS count_inserted_rows ON threads;
+DROP TRIGGER IF EXISTS count_deleted_rows ON threads;
+DROP TRIGGER IF EXISTS count_truncated_rows ON threads;
+DROP TRIGGER IF EXISTS count_inserted_rows ON posts;
+DROP TRIGGER IF EXISTS count_deleted_rows ON posts;
+DROP TRIGGER IF EXISTS count_truncated_rows ON posts;
+
+DROP FUNCTION IF EXISTS count_inserted_rows();
+DROP FUNCTION IF EXISTS count_deleted_rows();
+DROP FUNCTION IF EXISTS count_truncated_rows();
+
+DROP TABLE IF EXISTS service_counters;
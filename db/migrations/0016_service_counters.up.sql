@@ -0,0 +1,109 @@
+-- счётчики строк для /service/status, чтобы не считать COUNT(*) по всем
+-- таблицам на каждый запрос; name совпадает с именем таблицы. Счётчик
+-- таблицы разбит на 16 строк-шардов, каждое соединение пишет в свой шард,
+-- чтобы параллельные вставки не ждали блокировку одной строки до коммита;
+-- значение счётчика - сумма шардов
+CREATE TABLE IF NOT EXISTS service_counters (
+    name    TEXT    NOT NULL,
+    shard   INTEGER NOT NULL,
+    value   BIGINT  NOT NULL DEFAULT 0,
+    PRIMARY KEY (name, shard)
+);
+
+-- все шарды создаются сразу: триггеры только обновляют строки, и сверка,
+-- заблокировав их, не пропустит ни одной вставки
+INSERT INTO service_counters (name, shard, value)
+SELECT c.name, s.shard, CASE WHEN s.shard = 0 THEN c.value ELSE 0 END
+FROM (VALUES
+    ('users', (SELECT COUNT(*) FROM users)),
+    ('forum', (SELECT COUNT(*) FROM forum)),
+    ('threads', (SELECT COUNT(*) FROM threads)),
+    ('posts', (SELECT COUNT(*) FROM posts))
+) AS c(name, value), generate_series(0, 15) AS s(shard)
+ON CONFLICT (name, shard) DO NOTHING;
+
+
+-- вставка -> прибавить число вставленных строк к шарду соединения; триггер
+-- на уровне оператора, так что пачка постов обновляет счётчик один раз
+CREATE OR REPLACE FUNCTION count_inserted_rows() RETURNS TRIGGER AS $count_inserted_rows$
+BEGIN
+    UPDATE service_counters SET
+        value = value + (SELECT COUNT(*) FROM inserted_rows)
+    WHERE name = TG_TABLE_NAME AND shard = pg_backend_pid() % 16;
+
+    RETURN NULL;
+END;
+$count_inserted_rows$ LANGUAGE plpgsql;
+
+
+-- удаление, в том числе каскадное -> вычесть число удалённых строк
+CREATE OR REPLACE FUNCTION count_deleted_rows() RETURNS TRIGGER AS $count_deleted_rows$
+BEGIN
+    UPDATE service_counters SET
+        value = value - (SELECT COUNT(*) FROM deleted_rows)
+    WHERE name = TG_TABLE_NAME AND shard = pg_backend_pid() % 16;
+
+    RETURN NULL;
+END;
+$count_deleted_rows$ LANGUAGE plpgsql;
+
+
+-- TRUNCATE из /service/clear -> обнулить
+CREATE OR REPLACE FUNCTION count_truncated_rows() RETURNS TRIGGER AS $count_truncated_rows$
+BEGIN
+    UPDATE service_counters SET
+        value = 0
+    WHERE name = TG_TABLE_NAME;
+
+    RETURN NULL;
+END;
+$count_truncated_rows$ LANGUAGE plpgsql;
+
+
+DROP TRIGGER IF EXISTS count_inserted_rows ON users;
+CREATE TRIGGER count_inserted_rows AFTER INSERT ON users
+    REFERENCING NEW TABLE AS inserted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_inserted_rows();
+DROP TRIGGER IF EXISTS count_deleted_rows ON users;
+CREATE TRIGGER count_deleted_rows AFTER DELETE ON users
+    REFERENCING OLD TABLE AS deleted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_deleted_rows();
+DROP TRIGGER IF EXISTS count_truncated_rows ON users;
+CREATE TRIGGER count_truncated_rows AFTER TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_truncated_rows();
+
+DROP TRIGGER IF EXISTS count_inserted_rows ON forum;
+CREATE TRIGGER count_inserted_rows AFTER INSERT ON forum
+    REFERENCING NEW TABLE AS inserted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_inserted_rows();
+DROP TRIGGER IF EXISTS count_deleted_rows ON forum;
+CREATE TRIGGER count_deleted_rows AFTER DELETE ON forum
+    REFERENCING OLD TABLE AS deleted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_deleted_rows();
+DROP TRIGGER IF EXISTS count_truncated_rows ON forum;
+CREATE TRIGGER count_truncated_rows AFTER TRUNCATE ON forum
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_truncated_rows();
+
+DROP TRIGGER IF EXISTS count_inserted_rows ON threads;
+CREATE TRIGGER count_inserted_rows AFTER INSERT ON threads
+    REFERENCING NEW TABLE AS inserted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_inserted_rows();
+DROP TRIGGER IF EXISTS count_deleted_rows ON threads;
+CREATE TRIGGER count_deleted_rows AFTER DELETE ON threads
+    REFERENCING OLD TABLE AS deleted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_deleted_rows();
+DROP TRIGGER IF EXISTS count_truncated_rows ON threads;
+CREATE TRIGGER count_truncated_rows AFTER TRUNCATE ON threads
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_truncated_rows();
+
+DROP TRIGGER IF EXISTS count_inserted_rows ON posts;
+CREATE TRIGGER count_inserted_rows AFTER INSERT ON posts
+    REFERENCING NEW TABLE AS inserted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_inserted_rows();
+DROP TRIGGER IF EXISTS count_deleted_rows ON posts;
+CREATE TRIGGER count_deleted_rows AFTER DELETE ON posts
+    REFERENCING OLD TABLE AS deleted_rows
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_deleted_rows();
+DROP TRIGGER IF EXISTS count_truncated_rows ON posts;
+CREATE TRIGGER count_truncated_rows AFTER TRUNCATE ON posts
+    FOR EACH STATEMENT EXECUTE PROCEDURE count_truncated_rows();
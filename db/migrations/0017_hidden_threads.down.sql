@@ -0,0 +1,4 @@
+UPDATE threads SET status = 'archived' WHERE status = 'hidden';
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_status_check;
+ALTER TABLE threads ADD CONSTRAINT threads_status_check CHECK (status IN ('open', 'closed', 'archived'));
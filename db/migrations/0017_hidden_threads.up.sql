@@ -0,0 +1,4 @@
+-- скрытый модератором тред не виден в списках, поиске и постах, писать в
+-- него, как и в закрытый, нельзя
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_status_check;
+ALTER TABLE threads ADD CONSTRAINT threads_status_check CHECK (status IN ('open', 'closed', 'archived', 'hidden'));
@@ -0,0 +1,6 @@
+-- выданные подтверждения /service/clear; подтверждение удаляется при
+-- первом использовании, поэтому перехваченный токен не очистит базу дважды
+CREATE TABLE IF NOT EXISTS clear_confirmations (
+    nonce       TEXT                        NOT NULL PRIMARY KEY,
+    expires     TIMESTAMP WITH TIME ZONE    NOT NULL
+);
@@ -4,8 +4,10 @@ go 1.17
 
 require (
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/jackc/pgx v3.6.2+incompatible
 	github.com/lib/pq v1.10.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
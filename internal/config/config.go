@@ -0,0 +1,259 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const maskedSecret = "******"
+
+// Modes of POST /service/clear.
+const (
+	// ClearDisabled refuses to clear anything.
+	ClearDisabled = "disabled"
+	// ClearAdmin lets admins clear after confirming with a token the
+	// first call hands out.
+	ClearAdmin = "admin"
+	// ClearTest lets anyone clear at once, as the functional tests and
+	// the benchmark expect.
+	ClearTest = "test"
+)
+
+type Config struct {
+	Database DatabaseConfig `json:"database"`
+	Server   ServerConfig   `json:"server"`
+	LogLevel string         `json:"log_level"`
+}
+
+type DatabaseConfig struct {
+	Host            string   `json:"host"`
+	Port            string   `json:"port"`
+	User            string   `json:"user"`
+	Password        string   `json:"password"`
+	Name            string   `json:"name"`
+	SSLMode         string   `json:"sslmode"`
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnectTimeout  Duration `json:"connect_timeout"`
+	QueryTimeout    Duration `json:"query_timeout"`
+}
+
+type ServerConfig struct {
+	Addr            string   `json:"addr"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	CursorSecret    string   `json:"cursor_secret"`
+	AdminToken      string   `json:"admin_token"`
+	EventRetention  Duration `json:"event_retention"`
+	ReadyTimeout    Duration `json:"ready_timeout"`
+	ClearMode       string   `json:"clear_mode"`
+	AuthDisabled    bool     `json:"auth_disabled"`
+	// BannedWords is the comma separated site-wide list of the content
+	// filters.
+	BannedWords string `json:"banned_words"`
+	// AllowedOrigins is the comma separated list of origins, such as
+	// https://forum.example.com, whose pages may open websockets. Empty
+	// allows only the service's own host and "*" any origin.
+	AllowedOrigins string `json:"allowed_origins"`
+}
+
+// Default returns the configuration the service used to hard-code, so an
+// empty environment keeps the old behaviour.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:            "127.0.0.1",
+			Port:            "5432",
+			User:            "ekasy",
+			Password:        "ekasy",
+			Name:            "forum",
+			SSLMode:         "",
+			MaxOpenConns:    100,
+			MaxIdleConns:    100,
+			ConnMaxLifetime: Duration(3 * time.Minute),
+			ConnectTimeout:  Duration(5 * time.Second),
+			QueryTimeout:    Duration(10 * time.Second),
+		},
+		Server: ServerConfig{
+			Addr:            ":5000",
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(2 * time.Minute),
+			ShutdownTimeout: Duration(15 * time.Second),
+			EventRetention:  Duration(time.Hour),
+			ReadyTimeout:    Duration(2 * time.Second),
+			ClearMode:       ClearDisabled,
+		},
+		LogLevel: "info",
+	}
+}
+
+func (dc *DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(dc.User, dc.Password),
+		Host:   net.JoinHostPort(dc.Host, dc.Port),
+		Path:   "/" + dc.Name,
+	}
+	if dc.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": []string{dc.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+func (c *Config) Validate() error {
+	errs := make([]string, 0)
+	if c.Database.Host == "" {
+		errs = append(errs, "database.host is empty")
+	}
+	if c.Database.Port == "" {
+		errs = append(errs, "database.port is empty")
+	}
+	if c.Database.User == "" {
+		errs = append(errs, "database.user is empty")
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, "database.name is empty")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, "database.max_open_conns must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, "database.max_idle_conns must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, "database.max_idle_conns must not exceed database.max_open_conns")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, "database.conn_max_lifetime must not be negative")
+	}
+	if c.Database.ConnectTimeout <= 0 {
+		errs = append(errs, "database.connect_timeout must be positive")
+	}
+	if c.Database.QueryTimeout < 0 {
+		errs = append(errs, "database.query_timeout must not be negative")
+	}
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Sprintf("server.addr %q is not host:port", c.Server.Addr))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 || c.Server.ShutdownTimeout < 0 {
+		errs = append(errs, "server timeouts must not be negative")
+	}
+	if c.Server.EventRetention < 0 {
+		errs = append(errs, "server.event_retention must not be negative")
+	}
+	if c.Server.ReadyTimeout <= 0 {
+		errs = append(errs, "server.ready_timeout must be positive")
+	}
+	for _, origin := range c.Server.AllowedOriginList() {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Sprintf("server.allowed_origins entry %q is not scheme://host[:port] or *", origin))
+		}
+	}
+	switch c.Server.ClearMode {
+	case ClearDisabled, ClearAdmin, ClearTest:
+	default:
+		errs = append(errs, fmt.Sprintf("server.clear_mode %q is not one of disabled, admin, test", c.Server.ClearMode))
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Sprintf("log_level %q is not one of debug, info, warn, error", c.LogLevel))
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateServe checks what only serving needs on top of Validate, so the
+// migrate and reconcile commands run without the serving secrets.
+func (c *Config) ValidateServe() error {
+	if !c.Server.AuthDisabled && c.Server.AdminToken == "" {
+		return fmt.Errorf("invalid config: server.admin_token must be set unless server.auth_disabled is")
+	}
+	return nil
+}
+
+// Masked returns a copy that is safe to print: every secret is replaced.
+func (c *Config) Masked() *Config {
+	masked := *c
+	if masked.Database.Password != "" {
+		masked.Database.Password = maskedSecret
+	}
+	if masked.Server.CursorSecret != "" {
+		masked.Server.CursorSecret = maskedSecret
+	}
+	if masked.Server.AdminToken != "" {
+		masked.Server.AdminToken = maskedSecret
+	}
+	return &masked
+}
+
+func (c *Config) String() string {
+	buf, err := json.MarshalIndent(c.Masked(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("could not print config: %s", err.Error())
+	}
+	return string(buf)
+}
+
+// BannedWordList splits BannedWords, skipping blanks.
+func (sc *ServerConfig) BannedWordList() []string {
+	return splitList(sc.BannedWords)
+}
+
+// AllowedOriginList splits AllowedOrigins, skipping blanks.
+func (sc *ServerConfig) AllowedOriginList() []string {
+	return splitList(sc.AllowedOrigins)
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Duration is a time.Duration written as "3m" or "500ms" in config files.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(buf []byte) error {
+	var raw string
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"3m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configFileEnv = "FORUM_CONFIG"
+
+// option binds one config value to its environment variable and command
+// line flag.
+type option struct {
+	env    string
+	flag   string
+	usage  string
+	isBool bool
+	set    func(cfg *Config, raw string) error
+}
+
+// flagValue keeps a flag's raw text for Load to apply after the file and
+// the environment. Bool flags may be given bare, as -auth-disabled.
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (fv *flagValue) String() string {
+	if fv == nil {
+		return ""
+	}
+	return fv.raw
+}
+
+func (fv *flagValue) Set(raw string) error {
+	fv.raw = raw
+	return nil
+}
+
+func (fv *flagValue) IsBoolFlag() bool {
+	return fv.isBool
+}
+
+func stringOption(env, name, usage string, field func(cfg *Config) *string) option {
+	return option{env: env, flag: name, usage: usage, set: func(cfg *Config, raw string) error {
+		*field(cfg) = raw
+		return nil
+	}}
+}
+
+func intOption(env, name, usage string, field func(cfg *Config) *int) option {
+	return option{env: env, flag: name, usage: usage, set: func(cfg *Config, raw string) error {
+		val, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = val
+		return nil
+	}}
+}
+
+func boolOption(env, name, usage string, field func(cfg *Config) *bool) option {
+	return option{env: env, flag: name, usage: usage, isBool: true, set: func(cfg *Config, raw string) error {
+		val, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = val
+		return nil
+	}}
+}
+
+func durationOption(env, name, usage string, field func(cfg *Config) *Duration) option {
+	return option{env: env, flag: name, usage: usage, set: func(cfg *Config, raw string) error {
+		val, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = Duration(val)
+		return nil
+	}}
+}
+
+var options = []option{
+	stringOption("FORUM_DB_HOST", "db-host", "postgres host",
+		func(cfg *Config) *string { return &cfg.Database.Host }),
+	stringOption("FORUM_DB_PORT", "db-port", "postgres port",
+		func(cfg *Config) *string { return &cfg.Database.Port }),
+	stringOption("FORUM_DB_USER", "db-user", "postgres user",
+		func(cfg *Config) *string { return &cfg.Database.User }),
+	stringOption("FORUM_DB_PASSWORD", "db-password", "postgres password",
+		func(cfg *Config) *string { return &cfg.Database.Password }),
+	stringOption("FORUM_DB_NAME", "db-name", "postgres database name",
+		func(cfg *Config) *string { return &cfg.Database.Name }),
+	stringOption("FORUM_DB_SSLMODE", "db-sslmode", "postgres sslmode",
+		func(cfg *Config) *string { return &cfg.Database.SSLMode }),
+	intOption("FORUM_DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum number of open connections",
+		func(cfg *Config) *int { return &cfg.Database.MaxOpenConns }),
+	intOption("FORUM_DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum number of idle connections",
+		func(cfg *Config) *int { return &cfg.Database.MaxIdleConns }),
+	durationOption("FORUM_DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a connection",
+		func(cfg *Config) *Duration { return &cfg.Database.ConnMaxLifetime }),
+	durationOption("FORUM_DB_CONNECT_TIMEOUT", "db-connect-timeout", "timeout of the initial database ping",
+		func(cfg *Config) *Duration { return &cfg.Database.ConnectTimeout }),
+	durationOption("FORUM_DB_QUERY_TIMEOUT", "db-query-timeout", "deadline for the queries of one request, 0 disables it",
+		func(cfg *Config) *Duration { return &cfg.Database.QueryTimeout }),
+	stringOption("FORUM_LISTEN_ADDR", "listen", "http listen address",
+		func(cfg *Config) *string { return &cfg.Server.Addr }),
+	durationOption("FORUM_READ_TIMEOUT", "read-timeout", "http read timeout",
+		func(cfg *Config) *Duration { return &cfg.Server.ReadTimeout }),
+	durationOption("FORUM_WRITE_TIMEOUT", "write-timeout", "http write timeout",
+		func(cfg *Config) *Duration { return &cfg.Server.WriteTimeout }),
+	durationOption("FORUM_IDLE_TIMEOUT", "idle-timeout", "http keep-alive idle timeout",
+		func(cfg *Config) *Duration { return &cfg.Server.IdleTimeout }),
+	durationOption("FORUM_SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to drain in-flight requests on shutdown",
+		func(cfg *Config) *Duration { return &cfg.Server.ShutdownTimeout }),
+	stringOption("FORUM_CURSOR_SECRET", "cursor-secret", "key signing pagination cursors, random per process when empty",
+		func(cfg *Config) *string { return &cfg.Server.CursorSecret }),
+	stringOption("FORUM_ADMIN_TOKEN", "admin-token", "X-Admin-Token value granting admin rights, required unless auth is disabled",
+		func(cfg *Config) *string { return &cfg.Server.AdminToken }),
+	durationOption("FORUM_EVENT_RETENTION", "event-retention", "how long thread events stay available for resuming streams, 0 keeps them",
+		func(cfg *Config) *Duration { return &cfg.Server.EventRetention }),
+	durationOption("FORUM_READY_TIMEOUT", "ready-timeout", "deadline of the database checks behind /readyz",
+		func(cfg *Config) *Duration { return &cfg.Server.ReadyTimeout }),
+	stringOption("FORUM_CLEAR_MODE", "clear-mode", "who may call /service/clear: disabled, admin or test",
+		func(cfg *Config) *string { return &cfg.Server.ClearMode }),
+	stringOption("FORUM_BANNED_WORDS", "banned-words", "comma separated words the content filters ban site-wide",
+		func(cfg *Config) *string { return &cfg.Server.BannedWords }),
+	stringOption("FORUM_ALLOWED_ORIGINS", "allowed-origins", "comma separated origins whose pages may open websockets, * for any, empty for the own host only",
+		func(cfg *Config) *string { return &cfg.Server.AllowedOrigins }),
+	boolOption("FORUM_AUTH_DISABLED", "auth-disabled", "skip ownership checks so anyone may act as any user, for benchmark runs",
+		func(cfg *Config) *bool { return &cfg.Server.AuthDisabled }),
+	stringOption("FORUM_LOG_LEVEL", "log-level", "log level: debug, info, warn or error",
+		func(cfg *Config) *string { return &cfg.LogLevel }),
+}
+
+// Loader assembles the config from, in increasing priority: defaults, an
+// optional JSON or YAML file, environment variables and command line flags.
+type Loader struct {
+	fs      *flag.FlagSet
+	file    *string
+	flagged map[string]*flagValue
+}
+
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{
+		fs:      fs,
+		file:    fs.String("config", "", fmt.Sprintf("path to JSON or YAML config file (env %s)", configFileEnv)),
+		flagged: make(map[string]*flagValue),
+	}
+	for _, opt := range options {
+		l.flagged[opt.flag] = &flagValue{isBool: opt.isBool}
+		fs.Var(l.flagged[opt.flag], opt.flag, fmt.Sprintf("%s (env %s)", opt.usage, opt.env))
+	}
+	return l
+}
+
+// Load must be called after the flag set has been parsed.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+
+	path := *l.file
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range options {
+		raw, ok := os.LookupEnv(opt.env)
+		if !ok {
+			continue
+		}
+		if err := opt.set(cfg, raw); err != nil {
+			return nil, fmt.Errorf("env %s: %w", opt.env, err)
+		}
+	}
+
+	visited := make(map[string]bool)
+	l.fs.Visit(func(f *flag.Flag) {
+		visited[f.Name] = true
+	})
+	for _, opt := range options {
+		if !visited[opt.flag] {
+			continue
+		}
+		if err := opt.set(cfg, l.flagged[opt.flag].raw); err != nil {
+			return nil, fmt.Errorf("flag -%s: %w", opt.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		buf, err = yamlToJSON(buf)
+		if err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+	if err = json.Unmarshal(buf, cfg); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// yamlToJSON lets YAML files share the json tags and decoders of Config.
+func yamlToJSON(buf []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
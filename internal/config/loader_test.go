@@ -0,0 +1,44 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLoaderFlags(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		authDisabled bool
+	}{
+		{"bare bool", []string{"-auth-disabled"}, true},
+		{"explicit true", []string{"-auth-disabled=true"}, true},
+		{"explicit false", []string{"-auth-disabled=false", "-admin-token", "secret"}, false},
+		{"unset", []string{"-admin-token", "secret"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			loader := NewLoader(fs)
+			if err := fs.Parse(append(tt.args, "-listen", ":5001", "migrate")); err != nil {
+				t.Fatalf("Parse() = %v", err)
+			}
+			cfg, err := loader.Load()
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if cfg.Server.AuthDisabled != tt.authDisabled {
+				t.Errorf("AuthDisabled = %v, want %v", cfg.Server.AuthDisabled, tt.authDisabled)
+			}
+			if cfg.Server.Addr != ":5001" {
+				t.Errorf("Addr = %q, want the flag after the bool one", cfg.Server.Addr)
+			}
+			if args := fs.Args(); len(args) != 1 || args[0] != "migrate" {
+				t.Errorf("Args() = %v, want [migrate]", args)
+			}
+		})
+	}
+}
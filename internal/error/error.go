@@ -1,9 +1,15 @@
 package error
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+)
 
+// CustomError is an error a client may see. Code is the HTTP status it is
+// answered with and Reason a stable machine readable identifier.
 type CustomError struct {
 	Code    int
+	Reason  string
 	Message string
 }
 
@@ -11,6 +17,50 @@ func (ce CustomError) Error() string {
 	return fmt.Sprintf("error: code: %d message: %s", ce.Code, ce.Message)
 }
 
+// Is reports errors of the same reason as equal, so a CustomError with a
+// more specific message still matches the predefined value.
+func (ce CustomError) Is(target error) bool {
+	t, ok := target.(CustomError)
+	return ok && t.Reason == ce.Reason
+}
+
+// WithMessage returns the same error with a more specific message.
+func (ce CustomError) WithMessage(format string, args ...interface{}) CustomError {
+	ce.Message = fmt.Sprintf(format, args...)
+	return ce
+}
+
+// Wrap attaches the underlying cause. The result matches ce with errors.Is
+// and errors.As while errors.Unwrap still reaches the cause.
+func (ce CustomError) Wrap(cause error) error {
+	return &wrappedError{kind: ce, cause: cause}
+}
+
+type wrappedError struct {
+	kind  CustomError
+	cause error
+}
+
+func (we *wrappedError) Error() string {
+	return fmt.Sprintf("%s: %s", we.kind.Error(), we.cause.Error())
+}
+
+func (we *wrappedError) Unwrap() error {
+	return we.cause
+}
+
+func (we *wrappedError) Is(target error) bool {
+	return we.kind.Is(target)
+}
+
+func (we *wrappedError) As(target interface{}) bool {
+	ce, ok := target.(*CustomError)
+	if ok {
+		*ce = we.kind
+	}
+	return ok
+}
+
 func GenerateError(code int, message string) *CustomError {
 	return &CustomError{
 		Code: code, Message: message,
@@ -19,77 +69,303 @@ func GenerateError(code int, message string) *CustomError {
 
 var (
 	InternalDbError CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusInternalServerError,
+		Reason:  "internal_db_error",
 		Message: "internal database error",
 	}
 
 	RollbackError CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusInternalServerError,
+		Reason:  "rollback_error",
 		Message: "rollback databse error",
 	}
 
 	CommitError CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusInternalServerError,
+		Reason:  "commit_error",
 		Message: "commit database error",
 	}
 
+	RequestCanceled CustomError = CustomError{
+		Code:    http.StatusServiceUnavailable,
+		Reason:  "request_canceled",
+		Message: "request was canceled",
+	}
+
+	QueryTimeout CustomError = CustomError{
+		Code:    http.StatusGatewayTimeout,
+		Reason:  "query_timeout",
+		Message: "database query timed out",
+	}
+
+	InvalidBody CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_body",
+		Message: "invalid request body",
+	}
+
+	InvalidCursor CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_cursor",
+		Message: "cursor is malformed or does not match the query",
+	}
+
+	InvalidReport CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_report",
+		Message: "report needs a reporter and a category: spam, abuse, off_topic or other",
+	}
+
+	InvalidFilter CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_filter",
+		Message: "filter actions are reject, mask or hold and limits must not be negative",
+	}
+
+	InvalidBan CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_ban",
+		Message: "ban needs a nickname and an expiry in the future",
+	}
+
+	InvalidSearchQuery CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_search_query",
+		Message: "search query has no words to look for",
+	}
+
+	Unauthorized CustomError = CustomError{
+		Code:    http.StatusUnauthorized,
+		Reason:  "unauthorized",
+		Message: "missing or invalid bearer token",
+	}
+
+	Forbidden CustomError = CustomError{
+		Code:    http.StatusForbidden,
+		Reason:  "forbidden",
+		Message: "not allowed to perform this action",
+	}
+
+	UserBanned CustomError = CustomError{
+		Code:    http.StatusForbidden,
+		Reason:  "user_banned",
+		Message: "user is banned from this forum",
+	}
+
+	UserSuspended CustomError = CustomError{
+		Code:    http.StatusForbidden,
+		Reason:  "user_suspended",
+		Message: "user is suspended",
+	}
+
+	ReportAlreadyHandled CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "report_already_handled",
+		Message: "report was already resolved or dismissed",
+	}
+
 	NicknameAlreadyExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusConflict,
+		Reason:  "nickname_already_exist",
 		Message: "user with this nickname already exist",
 	}
 
 	EmailAlreadyExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusConflict,
+		Reason:  "email_already_exist",
 		Message: "user with this email already exist",
 	}
 
 	NoRows CustomError = CustomError{
-		Code:    404,
+		Code:    http.StatusNotFound,
+		Reason:  "not_found",
 		Message: "no rows in result set",
 	}
 
 	ConflictData CustomError = CustomError{
-		Code:    409,
+		Code:    http.StatusConflict,
+		Reason:  "conflict",
 		Message: "",
 	}
 
 	ForumAlreadyExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusConflict,
+		Reason:  "forum_already_exist",
 		Message: "forum with this slug already exist",
 	}
 
 	UserNotExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusNotFound,
+		Reason:  "user_not_exist",
 		Message: "user not exist",
 	}
 
 	AuthorNotExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusNotFound,
+		Reason:  "author_not_exist",
 		Message: "forum with this author not exist",
 	}
 
 	ForumNotExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusNotFound,
+		Reason:  "forum_not_exist",
 		Message: "forum with this slug not exist",
 	}
 
 	ThreadAlreadyExist CustomError = CustomError{
-		Code:    500,
-		Message: "thread with this slug not exist",
+		Code:    http.StatusConflict,
+		Reason:  "thread_already_exist",
+		Message: "thread with this slug already exist",
 	}
 
 	ThreadNotExists CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusNotFound,
+		Reason:  "thread_not_exist",
 		Message: "thread not exist",
 	}
 
+	ThreadClosed CustomError = CustomError{
+		Code:    http.StatusForbidden,
+		Reason:  "thread_closed",
+		Message: "thread is closed for posts and votes",
+	}
+
+	InvalidThreadStatus CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_thread_status",
+		Message: "thread status must be open, closed, archived or hidden",
+	}
+
 	ParentNotExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusConflict,
+		Reason:  "parent_not_exist",
 		Message: "parent's post not exist",
 	}
 
 	PostNotExist CustomError = CustomError{
-		Code:    500,
+		Code:    http.StatusNotFound,
+		Reason:  "post_not_exist",
 		Message: "post not exist",
 	}
+
+	PostDeleted CustomError = CustomError{
+		Code:    http.StatusGone,
+		Reason:  "post_deleted",
+		Message: "post was deleted",
+	}
+
+	RevisionNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "revision_not_exist",
+		Message: "post revision not exist",
+	}
+
+	ReportNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "report_not_exist",
+		Message: "report not exist",
+	}
+
+	HeldContentNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "held_content_not_exist",
+		Message: "held content not exist",
+	}
+
+	BanNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "ban_not_exist",
+		Message: "ban not exist",
+	}
+
+	ModeratorNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "moderator_not_exist",
+		Message: "moderator not exist",
+	}
+
+	TokenNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "token_not_exist",
+		Message: "api token not exist",
+	}
+
+	WebhookNotExist CustomError = CustomError{
+		Code:    http.StatusNotFound,
+		Reason:  "webhook_not_exist",
+		Message: "webhook not exist",
+	}
+
+	InvalidWebhook CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_webhook",
+		Message: "webhook url must be an absolute http or https url",
+	}
+
+	VoteAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "vote_already_exist",
+		Message: "vote of this user for this thread already exist",
+	}
+
+	UserAlreadyInForum CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "user_already_in_forum",
+		Message: "user already listed in this forum",
+	}
+
+	ModeratorAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "moderator_already_exist",
+		Message: "user already moderates this forum",
+	}
+
+	BanAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "ban_already_exist",
+		Message: "user is already banned from this forum",
+	}
+
+	SubscriptionAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "subscription_already_exist",
+		Message: "user already follows this thread",
+	}
+
+	MentionAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "mention_already_exist",
+		Message: "post already mentions this user",
+	}
+
+	TokenAlreadyExist CustomError = CustomError{
+		Code:    http.StatusConflict,
+		Reason:  "token_already_exist",
+		Message: "api token already exist",
+	}
+
+	ClearDisabled CustomError = CustomError{
+		Code:    http.StatusForbidden,
+		Reason:  "clear_disabled",
+		Message: "clearing the service is disabled",
+	}
+
+	InvalidConfirmation CustomError = CustomError{
+		Code:    http.StatusBadRequest,
+		Reason:  "invalid_confirmation",
+		Message: "confirmation token is invalid, expired or for another scope",
+	}
+
+	ContentRejected CustomError = CustomError{
+		Code:    http.StatusUnprocessableEntity,
+		Reason:  "content_rejected",
+		Message: "content breaks a filter rule of the forum",
+	}
+
+	// ContentHeld is no failure: the content waits for the moderators.
+	ContentHeld CustomError = CustomError{
+		Code:    http.StatusAccepted,
+		Reason:  "content_held",
+		Message: "content is held for review by the forum moderators",
+	}
 )
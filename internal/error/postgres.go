@@ -0,0 +1,123 @@
+package error
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx"
+)
+
+// SQLSTATE codes of the integrity violations the schema can raise.
+const (
+	notNullViolation    = "23502"
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+)
+
+// constraintErrors maps constraint names from the schema onto the error a
+// violation of that constraint means for the caller. Renaming a constraint
+// in the schema requires renaming it here as well; the tests list the
+// constraints left out on purpose.
+var constraintErrors = map[string]CustomError{
+	"users_pkey":                         NicknameAlreadyExist,
+	"users_email_key":                    EmailAlreadyExist,
+	"forum_pkey":                         ForumAlreadyExist,
+	"forum_author_fkey":                  UserNotExist,
+	"threads_pkey":                       ThreadAlreadyExist,
+	"index_threads_slug":                 ThreadAlreadyExist,
+	"threads_author_fkey":                AuthorNotExist,
+	"threads_forum_fkey":                 ForumNotExist,
+	"threads_status_check":               InvalidThreadStatus,
+	"posts_author_fkey":                  UserNotExist,
+	"posts_forum_fkey":                   ForumNotExist,
+	"posts_thread_fkey":                  ThreadNotExists,
+	"votes_pkey":                         VoteAlreadyExist,
+	"votes_nickname_fkey":                UserNotExist,
+	"votes_thread_fkey":                  ThreadNotExists,
+	"forum_users_pkey":                   UserAlreadyInForum,
+	"forum_users_nickname_fkey":          UserNotExist,
+	"forum_users_forum_fkey":             ForumNotExist,
+	"post_revisions_post_fkey":           PostNotExist,
+	"thread_events_thread_fkey":          ThreadNotExists,
+	"webhooks_forum_fkey":                ForumNotExist,
+	"webhook_outbox_webhook_fkey":        WebhookNotExist,
+	"webhook_deliveries_webhook_fkey":    WebhookNotExist,
+	"thread_subscriptions_pkey":          SubscriptionAlreadyExist,
+	"thread_subscriptions_thread_fkey":   ThreadNotExists,
+	"thread_subscriptions_nickname_fkey": UserNotExist,
+	"notifications_nickname_fkey":        UserNotExist,
+	"notifications_post_fkey":            PostNotExist,
+	"notifications_thread_fkey":          ThreadNotExists,
+	"post_mentions_pkey":                 MentionAlreadyExist,
+	"post_mentions_post_fkey":            PostNotExist,
+	"post_mentions_nickname_fkey":        UserNotExist,
+	"api_tokens_hash_key":                TokenAlreadyExist,
+	"api_tokens_nickname_fkey":           UserNotExist,
+	"forum_moderators_pkey":              ModeratorAlreadyExist,
+	"forum_moderators_forum_fkey":        ForumNotExist,
+	"forum_moderators_nickname_fkey":     UserNotExist,
+	"forum_bans_pkey":                    BanAlreadyExist,
+	"forum_bans_forum_fkey":              ForumNotExist,
+	"forum_bans_nickname_fkey":           UserNotExist,
+	"reports_post_fkey":                  PostNotExist,
+	"reports_thread_fkey":                ThreadNotExists,
+	"reports_forum_fkey":                 ForumNotExist,
+	"reports_reporter_fkey":              UserNotExist,
+	"reports_category_check":             InvalidReport,
+	"forum_filters_forum_fkey":           ForumNotExist,
+	"held_content_forum_fkey":            ForumNotExist,
+	"held_content_thread_fkey":           ThreadNotExists,
+}
+
+// columnErrors maps columns whose NOT NULL violation signals a missing
+// referenced row, as happens when a subselect for the value finds nothing.
+var columnErrors = map[string]CustomError{
+	"parent": ParentNotExist,
+}
+
+// FromDb translates an error returned by database/sql. sql.ErrNoRows
+// becomes notFound and a violation of a known constraint becomes its
+// CustomError. The second result is false for unexpected failures, so the
+// caller knows the original error is worth logging.
+func FromDb(err error, notFound CustomError) (CustomError, bool) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return notFound, true
+	case errors.Is(err, context.Canceled):
+		return RequestCanceled, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return QueryTimeout, false
+	}
+
+	pgErr, ok := asPgError(err)
+	if !ok {
+		return InternalDbError, false
+	}
+
+	switch pgErr.Code {
+	case uniqueViolation, foreignKeyViolation, checkViolation:
+		if ce, ok := constraintErrors[pgErr.ConstraintName]; ok {
+			return ce, true
+		}
+	case notNullViolation:
+		if ce, ok := columnErrors[pgErr.ColumnName]; ok {
+			return ce, true
+		}
+	}
+	return InternalDbError, false
+}
+
+func asPgError(err error) (pgx.PgError, bool) {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr, true
+	}
+
+	var pgErrPtr *pgx.PgError
+	if errors.As(err, &pgErrPtr) && pgErrPtr != nil {
+		return *pgErrPtr, true
+	}
+	return pgx.PgError{}, false
+}
@@ -0,0 +1,283 @@
+package error
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx"
+)
+
+func TestFromDbConstraints(t *testing.T) {
+	tests := []struct {
+		constraint string
+		code       string
+		want       CustomError
+	}{
+		{"users_pkey", uniqueViolation, NicknameAlreadyExist},
+		{"users_email_key", uniqueViolation, EmailAlreadyExist},
+		{"forum_pkey", uniqueViolation, ForumAlreadyExist},
+		{"forum_author_fkey", foreignKeyViolation, UserNotExist},
+		{"threads_pkey", uniqueViolation, ThreadAlreadyExist},
+		{"index_threads_slug", uniqueViolation, ThreadAlreadyExist},
+		{"threads_author_fkey", foreignKeyViolation, AuthorNotExist},
+		{"threads_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"threads_status_check", checkViolation, InvalidThreadStatus},
+		{"posts_author_fkey", foreignKeyViolation, UserNotExist},
+		{"posts_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"posts_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"votes_pkey", uniqueViolation, VoteAlreadyExist},
+		{"votes_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"votes_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"forum_users_pkey", uniqueViolation, UserAlreadyInForum},
+		{"forum_users_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"forum_users_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"post_revisions_post_fkey", foreignKeyViolation, PostNotExist},
+		{"thread_events_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"webhooks_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"webhook_outbox_webhook_fkey", foreignKeyViolation, WebhookNotExist},
+		{"webhook_deliveries_webhook_fkey", foreignKeyViolation, WebhookNotExist},
+		{"thread_subscriptions_pkey", uniqueViolation, SubscriptionAlreadyExist},
+		{"thread_subscriptions_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"thread_subscriptions_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"notifications_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"notifications_post_fkey", foreignKeyViolation, PostNotExist},
+		{"notifications_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"post_mentions_pkey", uniqueViolation, MentionAlreadyExist},
+		{"post_mentions_post_fkey", foreignKeyViolation, PostNotExist},
+		{"post_mentions_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"api_tokens_hash_key", uniqueViolation, TokenAlreadyExist},
+		{"api_tokens_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"forum_moderators_pkey", uniqueViolation, ModeratorAlreadyExist},
+		{"forum_moderators_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"forum_moderators_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"forum_bans_pkey", uniqueViolation, BanAlreadyExist},
+		{"forum_bans_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"forum_bans_nickname_fkey", foreignKeyViolation, UserNotExist},
+		{"reports_post_fkey", foreignKeyViolation, PostNotExist},
+		{"reports_thread_fkey", foreignKeyViolation, ThreadNotExists},
+		{"reports_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"reports_reporter_fkey", foreignKeyViolation, UserNotExist},
+		{"reports_category_check", checkViolation, InvalidReport},
+		{"forum_filters_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"held_content_forum_fkey", foreignKeyViolation, ForumNotExist},
+		{"held_content_thread_fkey", foreignKeyViolation, ThreadNotExists},
+	}
+
+	covered := make(map[string]bool, len(tests))
+	for _, tt := range tests {
+		covered[tt.constraint] = true
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, known := FromDb(pgx.PgError{Code: tt.code, ConstraintName: tt.constraint}, NoRows)
+			if got != tt.want || !known {
+				t.Errorf("FromDb() = %v, %v; want %v, true", got.Reason, known, tt.want.Reason)
+			}
+		})
+	}
+	for constraint := range constraintErrors {
+		if !covered[constraint] {
+			t.Errorf("constraint %s has no test case", constraint)
+		}
+	}
+}
+
+// unmappedConstraints are the schema's constraints a request cannot break:
+// their violation is a bug and rightly a logged 500.
+var unmappedConstraints = map[string]string{
+	"posts_pkey":                     "serial id",
+	"post_revisions_pkey":            "revision numbers are taken under a row lock",
+	"thread_events_pkey":             "serial id",
+	"webhooks_pkey":                  "serial id",
+	"webhook_outbox_pkey":            "serial id",
+	"webhook_outbox_status_check":    "status is set by the dispatcher",
+	"webhook_deliveries_pkey":        "serial id",
+	"webhook_deliveries_outbox_fkey": "attempts are recorded for claimed items",
+	"notifications_pkey":             "serial id",
+	"api_tokens_pkey":                "serial id",
+	"reports_pkey":                   "serial id",
+	"reports_status_check":           "status is set by the usecase",
+	"forum_filters_pkey":             "filters are upserted",
+	"held_content_pkey":              "serial id",
+	"service_counters_pkey":          "rows are seeded by the migration",
+	"clear_confirmations_pkey":       "nonces are random",
+}
+
+var (
+	createTablePattern   = regexp.MustCompile(`(?i)^\s*CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	addConstraintPattern = regexp.MustCompile(`(?i)ALTER TABLE (?:IF EXISTS )?(\w+) ADD CONSTRAINT (\w+)`)
+	uniqueIndexPattern   = regexp.MustCompile(`(?i)CREATE UNIQUE INDEX (?:IF NOT EXISTS )?(\w+)`)
+	namedPattern         = regexp.MustCompile(`(?i)^\s*CONSTRAINT (\w+)`)
+	foreignKeyPattern    = regexp.MustCompile(`(?i)FOREIGN KEY \((\w+)\)`)
+	columnPattern        = regexp.MustCompile(`^\s*(\w+)\s`)
+	keywordPattern       = regexp.MustCompile(`(?i)\b(PRIMARY KEY|UNIQUE|REFERENCES|CHECK)\b`)
+)
+
+// schemaConstraints reads the constraint names out of the up migrations,
+// spelling implicit ones the way Postgres names them.
+func schemaConstraints(t *testing.T) map[string]bool {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "..", "db", "migrations", "*.up.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+
+	constraints := make(map[string]bool)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table := ""
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if i := strings.Index(line, "--"); i >= 0 {
+				line = line[:i]
+			}
+			if m := addConstraintPattern.FindStringSubmatch(line); m != nil {
+				constraints[m[2]] = true
+				continue
+			}
+			if m := uniqueIndexPattern.FindStringSubmatch(line); m != nil {
+				constraints[m[1]] = true
+				continue
+			}
+			if m := createTablePattern.FindStringSubmatch(line); m != nil {
+				table = m[1]
+				continue
+			}
+			if table == "" {
+				continue
+			}
+			if strings.HasPrefix(strings.TrimSpace(line), ")") {
+				table = ""
+				continue
+			}
+
+			if m := namedPattern.FindStringSubmatch(line); m != nil {
+				constraints[m[1]] = true
+				continue
+			}
+			if m := foreignKeyPattern.FindStringSubmatch(line); m != nil {
+				constraints[table+"_"+m[1]+"_fkey"] = true
+				continue
+			}
+			column := ""
+			if m := columnPattern.FindStringSubmatch(line); m != nil {
+				column = m[1]
+			}
+			for _, keyword := range keywordPattern.FindAllString(line, -1) {
+				switch strings.ToUpper(keyword) {
+				case "PRIMARY KEY":
+					constraints[table+"_pkey"] = true
+				case "UNIQUE":
+					constraints[table+"_"+column+"_key"] = true
+				case "REFERENCES":
+					constraints[table+"_"+column+"_fkey"] = true
+				case "CHECK":
+					constraints[table+"_"+column+"_check"] = true
+				}
+			}
+		}
+		f.Close()
+		if err = scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return constraints
+}
+
+func TestSchemaConstraints(t *testing.T) {
+	schema := schemaConstraints(t)
+
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, mapped := constraintErrors[name]
+		_, unmapped := unmappedConstraints[name]
+		switch {
+		case mapped && unmapped:
+			t.Errorf("constraint %s is both mapped and listed as unmapped", name)
+		case !mapped && !unmapped:
+			t.Errorf("constraint %s is neither mapped nor listed as unmapped", name)
+		}
+	}
+
+	for name := range constraintErrors {
+		if !schema[name] {
+			t.Errorf("mapped constraint %s is not in the schema", name)
+		}
+	}
+	for name := range unmappedConstraints {
+		if !schema[name] {
+			t.Errorf("unmapped constraint %s is not in the schema", name)
+		}
+	}
+}
+
+func TestFromDbColumns(t *testing.T) {
+	tests := []struct {
+		column string
+		want   CustomError
+	}{
+		{"parent", ParentNotExist},
+	}
+
+	covered := make(map[string]bool, len(tests))
+	for _, tt := range tests {
+		covered[tt.column] = true
+		t.Run(tt.column, func(t *testing.T) {
+			got, known := FromDb(pgx.PgError{Code: notNullViolation, ColumnName: tt.column}, NoRows)
+			if got != tt.want || !known {
+				t.Errorf("FromDb() = %v, %v; want %v, true", got.Reason, known, tt.want.Reason)
+			}
+		})
+	}
+	for column := range columnErrors {
+		if !covered[column] {
+			t.Errorf("column %s has no test case", column)
+		}
+	}
+}
+
+func TestFromDb(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      CustomError
+		wantKnown bool
+	}{
+		{"no rows", sql.ErrNoRows, PostNotExist, true},
+		{"wrapped no rows", fmt.Errorf("select: %w", sql.ErrNoRows), PostNotExist, true},
+		{"canceled", context.Canceled, RequestCanceled, true},
+		{"deadline", context.DeadlineExceeded, QueryTimeout, false},
+		{"pointer", &pgx.PgError{Code: uniqueViolation, ConstraintName: "users_pkey"}, NicknameAlreadyExist, true},
+		{"wrapped", fmt.Errorf("insert: %w", pgx.PgError{Code: foreignKeyViolation, ConstraintName: "posts_thread_fkey"}), ThreadNotExists, true},
+		{"wrapped pointer", fmt.Errorf("insert: %w", &pgx.PgError{Code: checkViolation, ConstraintName: "threads_status_check"}), InvalidThreadStatus, true},
+		{"unknown constraint", pgx.PgError{Code: uniqueViolation, ConstraintName: "unknown_key"}, InternalDbError, false},
+		{"unknown column", pgx.PgError{Code: notNullViolation, ColumnName: "unknown"}, InternalDbError, false},
+		{"other code", pgx.PgError{Code: "42P01", ConstraintName: "users_pkey"}, InternalDbError, false},
+		{"nil pointer", (*pgx.PgError)(nil), InternalDbError, false},
+		{"other error", errors.New("connection refused"), InternalDbError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, known := FromDb(tt.err, PostNotExist)
+			if got != tt.want || known != tt.wantKnown {
+				t.Errorf("FromDb() = %v, %v; want %v, %v", got.Reason, known, tt.want.Reason, tt.wantKnown)
+			}
+		})
+	}
+}
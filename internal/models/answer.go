@@ -11,5 +11,6 @@ func ToBytes(body interface{}) []byte {
 }
 
 type Error struct {
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
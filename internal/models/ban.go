@@ -0,0 +1,37 @@
+package models
+
+// Restrictions that keep a user from posting, creating threads and voting.
+const (
+	RestrictionBan        = "ban"
+	RestrictionSuspension = "suspension"
+)
+
+// ForumBan keeps a user out of one forum until Expires, for good when it
+// is empty.
+type ForumBan struct {
+	Forum     string `json:"forum"`
+	Nickname  string `json:"nickname"`
+	Reason    string `json:"reason"`
+	Moderator string `json:"moderator,omitempty"`
+	Created   string `json:"created"`
+	Expires   string `json:"expires,omitempty"`
+}
+
+type ForumBanInput struct {
+	Nickname string `json:"nickname"`
+	Reason   string `json:"reason"`
+	Expires  string `json:"expires"`
+}
+
+// Suspension keeps a user out of every forum. Until is empty for a
+// suspension without end.
+type Suspension struct {
+	Nickname string `json:"nickname"`
+	Reason   string `json:"reason"`
+	Until    string `json:"until,omitempty"`
+}
+
+type SuspensionInput struct {
+	Reason string `json:"reason"`
+	Until  string `json:"until"`
+}
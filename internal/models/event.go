@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// Kinds of thread events.
+const (
+	EventThread   = "thread"
+	EventPost     = "post"
+	EventPostEdit = "post_edit"
+	EventVotes    = "votes"
+)
+
+// ThreadEvent is a change in a thread pushed to its subscribers. Id grows
+// with every event and is what clients resume from. Events of a hidden
+// thread are not pushed to the subscribers of its forum.
+type ThreadEvent struct {
+	Id      int64           `json:"id"`
+	Thread  int64           `json:"thread"`
+	Forum   string          `json:"forum"`
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+	Hidden  bool            `json:"-"`
+}
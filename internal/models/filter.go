@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// What a filter does with content breaking its rule.
+const (
+	FilterReject = "reject"
+	FilterMask   = "mask"
+	FilterHold   = "hold"
+)
+
+func ValidFilterAction(action string) bool {
+	switch action {
+	case FilterReject, FilterMask, FilterHold:
+		return true
+	}
+	return false
+}
+
+// Built-in filter rules.
+const (
+	RuleBannedWords = "banned_words"
+	RuleMaxLength   = "max_length"
+	RuleMaxLinks    = "max_links"
+	RuleShouting    = "shouting"
+)
+
+// FilterSettings configures the filters of one forum. BannedWords extend
+// the site-wide list and AllowedWords exempt words of it; zero limits and
+// a false Shouting turn the rule off.
+type FilterSettings struct {
+	Forum             string   `json:"forum"`
+	BannedWords       []string `json:"banned_words"`
+	AllowedWords      []string `json:"allowed_words"`
+	BannedWordsAction string   `json:"banned_words_action"`
+	MaxLength         int      `json:"max_length"`
+	MaxLengthAction   string   `json:"max_length_action"`
+	MaxLinks          int      `json:"max_links"`
+	MaxLinksAction    string   `json:"max_links_action"`
+	Shouting          bool     `json:"shouting"`
+	ShoutingAction    string   `json:"shouting_action"`
+}
+
+// DefaultFilterSettings are the settings of a forum nobody configured.
+func DefaultFilterSettings(forum string) *FilterSettings {
+	return &FilterSettings{
+		Forum:             forum,
+		BannedWords:       make([]string, 0),
+		AllowedWords:      make([]string, 0),
+		BannedWordsAction: FilterMask,
+		MaxLengthAction:   FilterReject,
+		MaxLinksAction:    FilterReject,
+		ShoutingAction:    FilterMask,
+	}
+}
+
+// Kinds of held content.
+const (
+	HeldPosts  = "posts"
+	HeldThread = "thread"
+)
+
+// HeldContent is a post batch or a thread a filter held for review.
+// Payload is the original request body.
+type HeldContent struct {
+	Id      int64           `json:"id"`
+	Kind    string          `json:"kind"`
+	Forum   string          `json:"forum"`
+	Thread  int64           `json:"thread,omitempty"`
+	Author  string          `json:"author"`
+	Rule    string          `json:"rule"`
+	Payload json.RawMessage `json:"payload"`
+	Created string          `json:"created"`
+}
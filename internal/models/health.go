@@ -0,0 +1,19 @@
+package models
+
+// Health statuses.
+const (
+	HealthOk          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Health is ok only when every check is.
+type Health struct {
+	Status string         `json:"status"`
+	Checks []*HealthCheck `json:"checks,omitempty"`
+}
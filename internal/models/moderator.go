@@ -0,0 +1,18 @@
+package models
+
+// Roles a user may hold in a forum. Site admins hold no role of their own,
+// they are recognised by the admin token.
+const (
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+)
+
+// Moderator is a user moderating a forum. The owner is listed with the
+// moderators it appointed.
+type Moderator struct {
+	Forum       string `json:"forum"`
+	Nickname    string `json:"nickname"`
+	Role        string `json:"role"`
+	AppointedBy string `json:"appointedBy,omitempty"`
+	Created     string `json:"created,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Kinds of notifications: a reply to one of the user's posts, a post
+// mentioning the user or a post in a thread the user follows.
+const (
+	NotificationReply   = "reply"
+	NotificationMention = "mention"
+	NotificationThread  = "thread"
+)
+
+type Notification struct {
+	Id      int64  `json:"id"`
+	Kind    string `json:"kind"`
+	Post    int64  `json:"post"`
+	Thread  int64  `json:"thread"`
+	Author  string `json:"author"`
+	IsRead  bool   `json:"isRead"`
+	Created string `json:"created"`
+}
+
+type NotificationsPage struct {
+	Unread        int64           `json:"unread"`
+	Notifications []*Notification `json:"notifications"`
+}
+
+// NotificationsRead lists the notifications to mark as read, all of them
+// when empty.
+type NotificationsRead struct {
+	Ids []int64 `json:"ids"`
+}
+
+type ThreadSubscription struct {
+	Nickname string `json:"nickname"`
+	Thread   int64  `json:"thread"`
+}
+
+type NotificationsQuery struct {
+	Nickname   string
+	Limit      int64
+	Since      int64
+	Sorting    string
+	Sign       string
+	UnreadOnly bool
+}
+
+func NewNotificationsQuery(vars map[string]string, query url.Values) *NotificationsQuery {
+	nq := &NotificationsQuery{
+		Nickname: vars["nickname"],
+		Limit:    100,
+		Since:    0,
+		Sorting:  "ASC",
+		Sign:     ">",
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err == nil {
+		nq.Limit = limit
+	}
+
+	since, err := strconv.ParseInt(query.Get("since"), 10, 64)
+	if err == nil {
+		nq.Since = since
+	}
+
+	unread, err := strconv.ParseBool(query.Get("unread"))
+	if err == nil {
+		nq.UnreadOnly = unread
+	}
+
+	// desc sorting
+	sorting, err := strconv.ParseBool(query.Get("desc"))
+	if err == nil && sorting {
+		nq.Sorting = "DESC"
+		nq.Sign = "<"
+	}
+	return nq
+}
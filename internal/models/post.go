@@ -1,20 +1,25 @@
 package models
 
 type Post struct {
-	Id       int64  `json:"id"`
-	Parent   int64  `json:"parent,omitempty"`
-	Author   string `json:"author"`
-	Message  string `json:"message"`
-	IsEdited bool   `json:"isEdited,omitempty"`
-	Forum    string `json:"forum"`
-	Thread   int64  `json:"thread"`
-	Created  string `json:"created"`
+	Id        int64    `json:"id"`
+	Parent    int64    `json:"parent,omitempty"`
+	Author    string   `json:"author"`
+	Message   string   `json:"message"`
+	IsEdited  bool     `json:"isEdited,omitempty"`
+	IsDeleted bool     `json:"isDeleted,omitempty"`
+	Forum     string   `json:"forum"`
+	Thread    int64    `json:"thread"`
+	Created   string   `json:"created"`
+	Mentions  []string `json:"mentions,omitempty"`
 }
 
 type PostInput struct {
 	Parent  int64  `json:"parent,omitempty"`
 	Author  string `json:"author"`
 	Message string `json:"message"`
+	// Mentions are the nicknames found in the message, not yet checked
+	// against the users.
+	Mentions []string `json:"-"`
 }
 
 type PostsInput struct {
@@ -24,4 +29,24 @@ type PostsInput struct {
 type PostUpdate struct {
 	Id      int64
 	Message string `json:"message"`
+	Editor  string `json:"editor,omitempty"`
+	// Mentions replace the ones of the post when the message changes.
+	Mentions []string `json:"-"`
 }
+
+type PostRevision struct {
+	Rev     int64  `json:"rev"`
+	Message string `json:"message"`
+	Editor  string `json:"editor"`
+	Created string `json:"created"`
+}
+
+type PostDiff struct {
+	Post int64  `json:"post"`
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// PostTombstone replaces the message of a deleted post.
+const PostTombstone = "[deleted]"
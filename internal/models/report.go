@@ -0,0 +1,95 @@
+package models
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Kinds of reported content.
+const (
+	ReportPost   = "post"
+	ReportThread = "thread"
+)
+
+// Statuses of a report. Only open reports wait in the moderation queue.
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+func ValidReportCategory(category string) bool {
+	switch category {
+	case "spam", "abuse", "off_topic", "other":
+		return true
+	}
+	return false
+}
+
+type Report struct {
+	Id       int64  `json:"id"`
+	Kind     string `json:"kind"`
+	Post     int64  `json:"post,omitempty"`
+	Thread   int64  `json:"thread"`
+	Forum    string `json:"forum"`
+	Reporter string `json:"reporter"`
+	Category string `json:"category"`
+	Comment  string `json:"comment,omitempty"`
+	Status   string `json:"status"`
+	Resolver string `json:"resolver,omitempty"`
+	Resolved string `json:"resolved,omitempty"`
+	Created  string `json:"created"`
+}
+
+type ReportInput struct {
+	Reporter string `json:"reporter"`
+	Category string `json:"category"`
+	Comment  string `json:"comment"`
+}
+
+// ReportResolution tells whether resolving a report hides the content.
+type ReportResolution struct {
+	Hide bool `json:"hide"`
+}
+
+type ReportsQuery struct {
+	Forum    string
+	Status   string
+	Category string
+	Kind     string
+	Limit    int64
+	Since    int64
+	Sorting  string
+	Sign     string
+}
+
+func NewReportsQuery(vars map[string]string, query url.Values) *ReportsQuery {
+	rq := &ReportsQuery{
+		Forum:    vars["slug"],
+		Status:   query.Get("status"),
+		Category: query.Get("category"),
+		Kind:     query.Get("kind"),
+		Limit:    100,
+		Since:    0,
+		Sorting:  "ASC",
+		Sign:     ">",
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err == nil {
+		rq.Limit = limit
+	}
+
+	since, err := strconv.ParseInt(query.Get("since"), 10, 64)
+	if err == nil {
+		rq.Since = since
+	}
+
+	// desc sorting
+	sorting, err := strconv.ParseBool(query.Get("desc"))
+	if err == nil && sorting {
+		rq.Sorting = "DESC"
+		rq.Sign = "<"
+	}
+	return rq
+}
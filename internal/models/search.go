@@ -0,0 +1,55 @@
+package models
+
+import (
+	"net/url"
+	"strconv"
+)
+
+type SearchQuery struct {
+	Text    string
+	Forum   string
+	Author  string
+	Since   string
+	Limit   int64
+	Sorting string
+	Sign    string
+}
+
+func NewSearchQuery(query url.Values) *SearchQuery {
+	sq := &SearchQuery{
+		Text:    query.Get("q"),
+		Forum:   query.Get("forum"),
+		Author:  query.Get("author"),
+		Since:   query.Get("since"),
+		Limit:   100,
+		Sorting: "ASC",
+		Sign:    ">=",
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err == nil {
+		sq.Limit = limit
+	}
+
+	// desc sorting
+	sorting, err := strconv.ParseBool(query.Get("desc"))
+	if err == nil && sorting {
+		sq.Sorting = "DESC"
+		sq.Sign = "<="
+	}
+	return sq
+}
+
+// SearchResult is a post or a thread matching a search. Snippet holds the
+// matched text, HTML escaped, with the hits wrapped in <b></b>.
+type SearchResult struct {
+	Type    string  `json:"type"`
+	Id      int64   `json:"id"`
+	Thread  int64   `json:"thread"`
+	Forum   string  `json:"forum"`
+	Author  string  `json:"author"`
+	Title   string  `json:"title,omitempty"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+	Created string  `json:"created"`
+}
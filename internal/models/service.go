@@ -6,3 +6,11 @@ type Service struct {
 	Thread int64 `json:"thread"`
 	Post   int64 `json:"post"`
 }
+
+// ClearConfirmation is handed out by the first call to clear in admin
+// mode; repeating the call with Token before Expires clears.
+type ClearConfirmation struct {
+	Forum   string `json:"forum,omitempty"`
+	Token   string `json:"confirm"`
+	Expires string `json:"expires"`
+}
@@ -13,6 +13,8 @@ type Thread struct {
 	Votes   int64  `json:"votes"`
 	Slug    string `json:"slug"`
 	Created string `json:"created"`
+	Status  string `json:"status,omitempty"`
+	Pinned  bool   `json:"pinned,omitempty"`
 }
 
 type ThreadInput struct {
@@ -28,6 +30,23 @@ const (
 	Layout string = "2006-01-02T15:04:05.000-07:00"
 )
 
+// Thread statuses. Only open threads accept posts and votes. Hidden threads
+// are left out of every read but the thread itself for its moderators.
+const (
+	ThreadOpen     = "open"
+	ThreadClosed   = "closed"
+	ThreadArchived = "archived"
+	ThreadHidden   = "hidden"
+)
+
+func ValidThreadStatus(status string) bool {
+	switch status {
+	case ThreadOpen, ThreadClosed, ThreadArchived, ThreadHidden:
+		return true
+	}
+	return false
+}
+
 func (ti *ThreadInput) ToThread(forumSlug string) *Thread {
 	dt := time.Now().Format(Layout)
 	if ti.Created == "" {
@@ -48,4 +67,7 @@ type ThreadUpdate struct {
 	Slug    string
 	Message string `json:"message"`
 	Title   string `json:"title"`
+	Status  string `json:"status"`
+	// Pinned is left as is when nil.
+	Pinned *bool `json:"pinned"`
 }
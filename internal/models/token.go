@@ -0,0 +1,15 @@
+package models
+
+// ApiToken authenticates its owner as a bearer token. Token is only shown
+// when it is issued.
+type ApiToken struct {
+	Id       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	Name     string `json:"name"`
+	Token    string `json:"token,omitempty"`
+	Created  string `json:"created"`
+}
+
+type ApiTokenInput struct {
+	Name string `json:"name"`
+}
@@ -5,6 +5,8 @@ type User struct {
 	Fullname string `json:"fullname" valid:"type(string),minstringlength(1)"`
 	About    string `json:"about" valid:"type(string),minstringlength(0)"`
 	Email    string `json:"email" valid:"email"`
+	// Token is the first API token, only shown when the user is created.
+	Token string `json:"token,omitempty"`
 }
 
 type UserUpdate struct {
@@ -13,6 +13,16 @@ type ThreadsVars struct {
 	Since     string
 	Sorting   string
 	Sign      string
+	After     *ThreadsCursor
+	// Pinned keeps only the threads moderators pinned.
+	Pinned bool
+}
+
+// ThreadsCursor is the sort key of the last thread of a page.
+type ThreadsCursor struct {
+	Created string `json:"c"`
+	Id      int64  `json:"i"`
+	Desc    bool   `json:"d,omitempty"`
 }
 
 func NewThreadsVars(vars map[string]string, query url.Values) *ThreadsVars {
@@ -45,6 +55,11 @@ func NewThreadsVars(vars map[string]string, query url.Values) *ThreadsVars {
 		}
 	}
 
+	pinned, err := strconv.ParseBool(query.Get("pinned"))
+	if err == nil {
+		tv.Pinned = pinned
+	}
+
 	return tv
 }
 
@@ -56,6 +71,17 @@ type ThreadsQuery struct {
 	Sort       string
 	Sign       string
 	Sorting    string
+	After      *PostsCursor
+}
+
+// PostsCursor is the sort key of the last post of a page. For the tree
+// sort the post's path is looked up by Id, for parent_tree Id is the root
+// of the last tree on the page.
+type PostsCursor struct {
+	Sort    string `json:"s"`
+	Created string `json:"c,omitempty"`
+	Id      int64  `json:"i"`
+	Desc    bool   `json:"d,omitempty"`
 }
 
 func NewThreadQuery(vars map[string]string, query url.Values) *ThreadsQuery {
@@ -111,6 +137,13 @@ type ForumUsersQuery struct {
 	Since     string
 	Sorting   string
 	Sign      string
+	After     *ForumUsersCursor
+}
+
+// ForumUsersCursor is the sort key of the last user of a page.
+type ForumUsersCursor struct {
+	Nickname string `json:"n"`
+	Desc     bool   `json:"d,omitempty"`
 }
 
 func NewForumUsersQuery(vars map[string]string, query url.Values) *ForumUsersQuery {
@@ -177,3 +210,39 @@ func NewPostQuery(vars map[string]string, r *http.Request) *PostQuery {
 
 	return pq
 }
+
+type MentionsQuery struct {
+	Nickname string
+	Limit    int64
+	Since    int64
+	Sorting  string
+	Sign     string
+}
+
+func NewMentionsQuery(vars map[string]string, query url.Values) *MentionsQuery {
+	mq := &MentionsQuery{
+		Nickname: vars["nickname"],
+		Limit:    100,
+		Since:    0,
+		Sorting:  "ASC",
+		Sign:     ">",
+	}
+
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err == nil {
+		mq.Limit = limit
+	}
+
+	since, err := strconv.ParseInt(query.Get("since"), 10, 64)
+	if err == nil {
+		mq.Since = since
+	}
+
+	// desc sorting
+	sorting, err := strconv.ParseBool(query.Get("desc"))
+	if err == nil && sorting {
+		mq.Sorting = "DESC"
+		mq.Sign = "<"
+	}
+	return mq
+}
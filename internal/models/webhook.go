@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// Webhook receives the events of a forum. An empty Events list subscribes
+// to every kind. Secret is only shown when the webhook is created.
+type Webhook struct {
+	Id      int64    `json:"id"`
+	Forum   string   `json:"forum"`
+	Url     string   `json:"url"`
+	Events  []string `json:"events"`
+	Secret  string   `json:"secret,omitempty"`
+	Created string   `json:"created"`
+}
+
+type WebhookInput struct {
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// Statuses of an outbox item.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// OutboxItem is one event waiting to be sent to one webhook.
+type OutboxItem struct {
+	Id       int64
+	Webhook  int64
+	Url      string
+	Secret   string
+	Forum    string
+	Thread   int64
+	Kind     string
+	Payload  json.RawMessage
+	Attempts int
+	Created  string
+}
+
+// WebhookPayload is the body POSTed to a webhook.
+type WebhookPayload struct {
+	Delivery int64           `json:"delivery"`
+	Kind     string          `json:"kind"`
+	Forum    string          `json:"forum"`
+	Thread   int64           `json:"thread"`
+	Created  string          `json:"created"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// WebhookAttempt is the outcome of one POST of an outbox item.
+type WebhookAttempt struct {
+	Id         int64  `json:"id"`
+	Delivery   int64  `json:"delivery"`
+	Kind       string `json:"kind"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Status     string `json:"status"`
+	Created    string `json:"created"`
+}
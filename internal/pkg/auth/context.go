@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	myerr "forum/internal/error"
+	"strings"
+)
+
+type principalKey struct{}
+
+type unenforcedKey struct{}
+
+// Principal is whoever the current request acts on behalf of.
+type Principal struct {
+	Admin bool
+	// Nickname is the user the bearer token was issued to.
+	Nickname string
+}
+
+// Authenticator resolves a bearer token to the nickname it was issued to.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the request's principal or nil for anonymous calls.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// IsAdmin reports admin rights, which every request has while ownership
+// checks are off.
+func IsAdmin(ctx context.Context) bool {
+	if !Enforced(ctx) {
+		return true
+	}
+	principal := FromContext(ctx)
+	return principal != nil && principal.Admin
+}
+
+// Nickname returns the authenticated user or "" for anonymous calls.
+func Nickname(ctx context.Context) string {
+	principal := FromContext(ctx)
+	if principal == nil {
+		return ""
+	}
+	return principal.Nickname
+}
+
+// WithoutEnforcement lets the request act as any user, which the benchmark
+// relies on.
+func WithoutEnforcement(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unenforcedKey{}, true)
+}
+
+// Enforced reports whether ownership checks apply to the request.
+func Enforced(ctx context.Context) bool {
+	unenforced, _ := ctx.Value(unenforcedKey{}).(bool)
+	return !unenforced
+}
+
+// Authorize checks that the request may act as nickname: admins and the
+// user themselves may, anyone may when enforcement is off.
+func Authorize(ctx context.Context, nickname string) error {
+	if IsAdmin(ctx) {
+		return nil
+	}
+	current := Nickname(ctx)
+	switch {
+	case current == "":
+		return myerr.Unauthorized
+	case !strings.EqualFold(current, nickname):
+		return myerr.Forbidden
+	}
+	return nil
+}
+
+// RoleResolver tells the role a user holds in a forum, "" for none.
+type RoleResolver interface {
+	ForumRole(ctx context.Context, forum string, nickname string) (string, error)
+}
+
+// AuthorizeModerator checks that the request may moderate the forum: site
+// admins and the forum's owner and moderators may, anyone may when
+// enforcement is off.
+func AuthorizeModerator(ctx context.Context, roles RoleResolver, forum string) error {
+	if IsAdmin(ctx) {
+		return nil
+	}
+	nickname := Nickname(ctx)
+	if nickname == "" {
+		return myerr.Unauthorized
+	}
+
+	role, err := roles.ForumRole(ctx, forum, nickname)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return myerr.Forbidden
+	}
+	return nil
+}
+
+// AuthorizeAuthor lets the author of some content through as well as
+// whoever moderates the forum it belongs to.
+func AuthorizeAuthor(ctx context.Context, roles RoleResolver, forum string, author string) error {
+	err := Authorize(ctx, author)
+	if err == nil || errors.Is(err, myerr.Unauthorized) {
+		return err
+	}
+	return AuthorizeModerator(ctx, roles, forum)
+}
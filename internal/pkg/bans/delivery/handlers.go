@@ -0,0 +1,129 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/bans"
+	"forum/internal/pkg/response"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type BanDelivery struct {
+	banUsecase bans.BanUsecase
+}
+
+func NewBanDelivery(banUsecase bans.BanUsecase) *BanDelivery {
+	return &BanDelivery{
+		banUsecase: banUsecase,
+	}
+}
+
+func (bd *BanDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/forum/{slug}/bans", bd.GetBansHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/bans", bd.BanUserHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/bans/{nickname}", bd.LiftBanHandler).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/suspension", bd.SuspendHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/suspension", bd.LiftSuspensionHandler).Methods(http.MethodDelete, http.MethodOptions)
+}
+
+func (bd *BanDelivery) GetBansHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	bans, err := bd.banUsecase.GetBans(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, bans)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (bd *BanDelivery) BanUserHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	input := &models.ForumBanInput{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	err = json.Unmarshal(buf, input)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	ban, err := bd.banUsecase.BanUser(r.Context(), slug, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, ban)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", input.Nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (bd *BanDelivery) LiftBanHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	err := bd.banUsecase.LiftBan(r.Context(), slug, mux.Vars(r)["nickname"])
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (bd *BanDelivery) SuspendHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	input := &models.SuspensionInput{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	if len(buf) != 0 {
+		err = json.Unmarshal(buf, input)
+		if err != nil {
+			response.Error(w, r, myerr.InvalidBody)
+			return
+		}
+	}
+
+	suspension, err := bd.banUsecase.Suspend(r.Context(), nickname, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, suspension)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (bd *BanDelivery) LiftSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	err := bd.banUsecase.LiftSuspension(r.Context(), nickname)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
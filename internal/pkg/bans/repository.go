@@ -0,0 +1,15 @@
+package bans
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type BanRepository interface {
+	SelectRestriction(ctx context.Context, forum string, nicknames []string) (string, string, error)
+	InsertBan(ctx context.Context, ban *models.ForumBan) error
+	DeleteBan(ctx context.Context, forum string, nickname string) error
+	SelectBans(ctx context.Context, forum string) ([]*models.ForumBan, error)
+	UpdateSuspension(ctx context.Context, suspension *models.Suspension) error
+	DeleteSuspension(ctx context.Context, nickname string) error
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/bans"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type BanRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewBanRepository(db *sql.DB, lg *logger.Logger) bans.BanRepository {
+	return &BanRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+// SelectRestriction finds one of the users banned from the forum or
+// suspended and tells which restriction applies. Both are "" when none.
+func (br *BanRepository) SelectRestriction(ctx context.Context, forum string, nicknames []string) (string, string, error) {
+	defer metrics.ObserveQuery("bans", "SelectRestriction", time.Now())
+
+	var nickname, kind string
+	row := br.db.QueryRowContext(
+		ctx,
+		`SELECT nickname, $3 FROM users
+		 WHERE nickname = ANY($2::CITEXT[]) AND isSuspended AND (suspendedUntil IS NULL OR suspendedUntil > NOW())
+		 UNION ALL
+		 SELECT nickname, $4 FROM forum_bans
+		 WHERE forum = $1 AND nickname = ANY($2::CITEXT[]) AND (expires IS NULL OR expires > NOW())
+		 LIMIT 1;`,
+		forum, pq.Array(nicknames), models.RestrictionSuspension, models.RestrictionBan)
+	err := row.Scan(&nickname, &kind)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			br.logger.Error(ctx, err.Error())
+		}
+		return "", "", dbErr
+	}
+	return nickname, kind, nil
+}
+
+// InsertBan replaces an earlier ban of the same user, expired or not.
+func (br *BanRepository) InsertBan(ctx context.Context, ban *models.ForumBan) error {
+	defer metrics.ObserveQuery("bans", "InsertBan", time.Now())
+
+	var expires sql.NullString
+	row := br.db.QueryRowContext(
+		ctx,
+		`INSERT INTO forum_bans (forum, nickname, reason, moderator, expires)
+		 VALUES (
+			COALESCE((SELECT slug FROM forum WHERE slug = $1), $1),
+			COALESCE((SELECT nickname FROM users WHERE nickname = $2), $2),
+			$3, $4, NULLIF($5, '')::TIMESTAMP WITH TIME ZONE
+		 )
+		 ON CONFLICT (forum, nickname) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			moderator = EXCLUDED.moderator,
+			created = NOW(),
+			expires = EXCLUDED.expires
+		 RETURNING forum, nickname, reason, moderator, created, expires;`,
+		ban.Forum, ban.Nickname, ban.Reason, ban.Moderator, ban.Expires)
+	err := row.Scan(&ban.Forum, &ban.Nickname, &ban.Reason, &ban.Moderator, &ban.Created, &expires)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			br.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	ban.Expires = expires.String
+	return nil
+}
+
+func (br *BanRepository) DeleteBan(ctx context.Context, forum string, nickname string) error {
+	defer metrics.ObserveQuery("bans", "DeleteBan", time.Now())
+
+	res, err := br.db.ExecContext(ctx, "DELETE FROM forum_bans WHERE forum = $1 AND nickname = $2;", forum, nickname)
+	if err != nil {
+		br.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		br.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	if deleted == 0 {
+		return myerr.BanNotExist
+	}
+	return nil
+}
+
+// SelectBans lists the bans in force, latest first.
+func (br *BanRepository) SelectBans(ctx context.Context, forum string) ([]*models.ForumBan, error) {
+	defer metrics.ObserveQuery("bans", "SelectBans", time.Now())
+
+	row := br.db.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1;", forum)
+	err := row.Scan(&forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			br.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	rows, err := br.db.QueryContext(
+		ctx,
+		`SELECT forum, nickname, reason, moderator, created, expires FROM forum_bans
+		 WHERE forum = $1 AND (expires IS NULL OR expires > NOW())
+		 ORDER BY created DESC, nickname;`,
+		forum)
+	if err != nil {
+		br.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	bans := make([]*models.ForumBan, 0)
+	for rows.Next() {
+		ban := &models.ForumBan{}
+		var expires sql.NullString
+		err = rows.Scan(&ban.Forum, &ban.Nickname, &ban.Reason, &ban.Moderator, &ban.Created, &expires)
+		if err != nil {
+			br.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		ban.Expires = expires.String
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+func (br *BanRepository) UpdateSuspension(ctx context.Context, suspension *models.Suspension) error {
+	defer metrics.ObserveQuery("bans", "UpdateSuspension", time.Now())
+
+	var until sql.NullString
+	row := br.db.QueryRowContext(
+		ctx,
+		`UPDATE users SET
+			isSuspended = TRUE,
+			suspendedUntil = NULLIF($2, '')::TIMESTAMP WITH TIME ZONE,
+			suspendReason = $3
+		 WHERE nickname = $1
+		 RETURNING nickname, suspendReason, suspendedUntil;`,
+		suspension.Nickname, suspension.Until, suspension.Reason)
+	err := row.Scan(&suspension.Nickname, &suspension.Reason, &until)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			br.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	suspension.Until = until.String
+	return nil
+}
+
+func (br *BanRepository) DeleteSuspension(ctx context.Context, nickname string) error {
+	defer metrics.ObserveQuery("bans", "DeleteSuspension", time.Now())
+
+	row := br.db.QueryRowContext(
+		ctx,
+		`UPDATE users SET isSuspended = FALSE, suspendedUntil = NULL, suspendReason = ''
+		 WHERE nickname = $1
+		 RETURNING nickname;`,
+		nickname)
+	err := row.Scan(&nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			br.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package bans
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+// BanChecker refuses users banned from a forum or suspended site-wide.
+type BanChecker interface {
+	CheckAllowed(ctx context.Context, forum string, nicknames ...string) error
+}
+
+type BanUsecase interface {
+	BanChecker
+	BanUser(ctx context.Context, forum string, input *models.ForumBanInput) (*models.ForumBan, error)
+	LiftBan(ctx context.Context, forum string, nickname string) error
+	GetBans(ctx context.Context, forum string) ([]*models.ForumBan, error)
+	Suspend(ctx context.Context, nickname string, input *models.SuspensionInput) (*models.Suspension, error)
+	LiftSuspension(ctx context.Context, nickname string) error
+}
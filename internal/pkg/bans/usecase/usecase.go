@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/bans"
+	"time"
+)
+
+type BanUsecase struct {
+	repo  bans.BanRepository
+	roles auth.RoleResolver
+}
+
+func NewBanUsecase(repo bans.BanRepository, roles auth.RoleResolver) bans.BanUsecase {
+	return &BanUsecase{
+		repo:  repo,
+		roles: roles,
+	}
+}
+
+// CheckAllowed answers UserSuspended or UserBanned when any of the users
+// may not write to the forum.
+func (bu *BanUsecase) CheckAllowed(ctx context.Context, forum string, nicknames ...string) error {
+	if len(nicknames) == 0 {
+		return nil
+	}
+	nickname, kind, err := bu.repo.SelectRestriction(ctx, forum, nicknames)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case models.RestrictionSuspension:
+		return myerr.UserSuspended.WithMessage("user %s is suspended", nickname)
+	case models.RestrictionBan:
+		return myerr.UserBanned.WithMessage("user %s is banned from forum %s", nickname, forum)
+	}
+	return nil
+}
+
+// BanUser is up to the moderators of the forum. Without an expiry the ban
+// lasts until it is lifted.
+func (bu *BanUsecase) BanUser(ctx context.Context, forum string, input *models.ForumBanInput) (*models.ForumBan, error) {
+	err := auth.AuthorizeModerator(ctx, bu.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	if input.Nickname == "" || !validExpiry(input.Expires) {
+		return nil, myerr.InvalidBan
+	}
+
+	ban := &models.ForumBan{
+		Forum:     forum,
+		Nickname:  input.Nickname,
+		Reason:    input.Reason,
+		Moderator: auth.Nickname(ctx),
+		Expires:   input.Expires,
+	}
+	err = bu.repo.InsertBan(ctx, ban)
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+func (bu *BanUsecase) LiftBan(ctx context.Context, forum string, nickname string) error {
+	err := auth.AuthorizeModerator(ctx, bu.roles, forum)
+	if err != nil {
+		return err
+	}
+	return bu.repo.DeleteBan(ctx, forum, nickname)
+}
+
+func (bu *BanUsecase) GetBans(ctx context.Context, forum string) ([]*models.ForumBan, error) {
+	err := auth.AuthorizeModerator(ctx, bu.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return bu.repo.SelectBans(ctx, forum)
+}
+
+// Suspend and LiftSuspension are reserved to admins.
+func (bu *BanUsecase) Suspend(ctx context.Context, nickname string, input *models.SuspensionInput) (*models.Suspension, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, myerr.Forbidden
+	}
+	if !validExpiry(input.Until) {
+		return nil, myerr.InvalidBan
+	}
+
+	suspension := &models.Suspension{
+		Nickname: nickname,
+		Reason:   input.Reason,
+		Until:    input.Until,
+	}
+	err := bu.repo.UpdateSuspension(ctx, suspension)
+	if err != nil {
+		return nil, err
+	}
+	return suspension, nil
+}
+
+func (bu *BanUsecase) LiftSuspension(ctx context.Context, nickname string) error {
+	if !auth.IsAdmin(ctx) {
+		return myerr.Forbidden
+	}
+	return bu.repo.DeleteSuspension(ctx, nickname)
+}
+
+// validExpiry accepts no expiry at all or an RFC 3339 time in the future.
+func validExpiry(expires string) bool {
+	if expires == "" {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, expires)
+	return err == nil && t.After(time.Now())
+}
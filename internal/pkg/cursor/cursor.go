@@ -0,0 +1,68 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	myerr "forum/internal/error"
+	"strings"
+)
+
+// Codec turns a page's sort key into an opaque token and back. Tokens are
+// signed, so clients can neither forge nor tamper with them.
+type Codec struct {
+	secret []byte
+}
+
+func NewCodec(secret string) *Codec {
+	return &Codec{
+		secret: []byte(secret),
+	}
+}
+
+// NewRandomCodec signs with a per-process secret; its tokens do not survive
+// a restart and are not accepted by other instances.
+func NewRandomCodec() (*Codec, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Codec{secret: secret}, nil
+}
+
+func (c *Codec) Encode(key interface{}) string {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func (c *Codec) Decode(token string, key interface{}) error {
+	sep := strings.IndexByte(token, '.')
+	if sep < 0 {
+		return myerr.InvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(token[:sep])
+	if err != nil {
+		return myerr.InvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(token[sep+1:])
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return myerr.InvalidCursor
+	}
+
+	if err = json.Unmarshal(payload, key); err != nil {
+		return myerr.InvalidCursor
+	}
+	return nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,235 @@
+// Package diff renders line based unified diffs of post revisions.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines kept around every change.
+const contextLines = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+	a, b int // line numbers in from and to, zero based
+}
+
+// Unified returns the difference between from and to in unified format.
+// Identical texts produce an empty string.
+func Unified(fromName, toName, from, to string) string {
+	ops := lineOps(splitLines(from), splitLines(to))
+	hunks := groupHunks(ops)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		writeHunk(&sb, hunk)
+	}
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineOps builds the edit script with Myers' algorithm after trimming the
+// common prefix and suffix. Texts further apart than maxEdits edits get
+// the plain script that deletes every line and inserts the new ones, so a
+// huge rewrite costs neither quadratic time nor memory.
+func lineOps(a, b []string) []op {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		ops = append(ops, op{kind: opEqual, line: a[i], a: i, b: i})
+	}
+	middle := myers(a[pre:len(a)-suf], b[pre:len(b)-suf])
+	if middle == nil {
+		middle = replaceAll(a[pre:len(a)-suf], b[pre:len(b)-suf])
+	}
+	for _, o := range middle {
+		o.a += pre
+		o.b += pre
+		ops = append(ops, o)
+	}
+	for i := suf; i > 0; i-- {
+		ops = append(ops, op{kind: opEqual, line: a[len(a)-i], a: len(a) - i, b: len(b) - i})
+	}
+	return ops
+}
+
+// maxEdits bounds the edit distance myers searches for. Its trace takes
+// memory quadratic in the bound, not in the length of the texts.
+const maxEdits = 1000
+
+// myers returns the shortest edit script turning a into b, or nil when it
+// is longer than maxEdits.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y;
+	// trace[d] keeps v for the diagonals -d..d after d edits.
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	trace := make([][]int, 0)
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		if n-m >= -d && n-m <= d && v[offset+n-m] >= n {
+			return backtrack(a, b, trace)
+		}
+	}
+	return nil
+}
+
+// backtrack walks the trace back from the end of both texts.
+func backtrack(a, b []string, trace [][]int) []op {
+	reached := func(d, k int) int {
+		return trace[d][k+d]
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && reached(d-1, k-1) < reached(d-1, k+1)) {
+			prevK = k + 1
+		}
+		prevX := reached(d-1, prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, line: a[x], a: x, b: y})
+		}
+		if x == prevX {
+			ops = append(ops, op{kind: opInsert, line: b[prevY], a: x, b: prevY})
+		} else {
+			ops = append(ops, op{kind: opDelete, line: a[prevX], a: prevX, b: y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{kind: opEqual, line: a[x], a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func replaceAll(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, op{kind: opDelete, line: line, a: i, b: 0})
+	}
+	for j, line := range b {
+		ops = append(ops, op{kind: opInsert, line: line, a: len(a), b: j})
+	}
+	return ops
+}
+
+// groupHunks cuts the edit script into runs of changes with their context,
+// merging changes that are closer than twice the context.
+func groupHunks(ops []op) [][]op {
+	hunks := make([][]op, 0)
+	start, end := -1, -1
+	for idx, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+		lo := idx - contextLines
+		if lo < 0 {
+			lo = 0
+		}
+		hi := idx + contextLines + 1
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		if start >= 0 && lo > end {
+			hunks = append(hunks, ops[start:end])
+			start = -1
+		}
+		if start < 0 {
+			start = lo
+		}
+		end = hi
+	}
+	if start >= 0 {
+		hunks = append(hunks, ops[start:end])
+	}
+	return hunks
+}
+
+func writeHunk(sb *strings.Builder, hunk []op) {
+	var aLen, bLen int
+	for _, o := range hunk {
+		if o.kind != opInsert {
+			aLen++
+		}
+		if o.kind != opDelete {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(hunk[0].a, aLen), hunkRange(hunk[0].b, bLen))
+	for _, o := range hunk {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// hunkRange follows the GNU convention: an empty range names the line
+// before it.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,202 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"both empty", "", "", ""},
+		{"changed line", "a\nb\nc\n", "a\nB\nc\n",
+			"--- rev 1\n+++ rev 2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"from empty", "", "x\n",
+			"--- rev 1\n+++ rev 2\n@@ -0,0 +1 @@\n+x\n"},
+		{"to empty", "x\ny\n", "",
+			"--- rev 1\n+++ rev 2\n@@ -1,2 +0,0 @@\n-x\n-y\n"},
+		{"missing final newline", "a\nb", "a\nb\nc",
+			"--- rev 1\n+++ rev 2\n@@ -1,2 +1,3 @@\n a\n b\n+c\n"},
+		{"context is cut", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\n2\n3\n4\n5\n6\n7\n8\nnine\n",
+			"--- rev 1\n+++ rev 2\n@@ -6,4 +6,4 @@\n 6\n 7\n 8\n-9\n+nine\n"},
+		{"distant changes", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"--- rev 1\n+++ rev 2\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("rev 1", "rev 2", tt.from, tt.to); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// script builds edit scripts from a string of op kinds.
+func script(kinds string) []op {
+	ops := make([]op, 0, len(kinds))
+	for _, kind := range kinds {
+		ops = append(ops, op{kind: opKind(kind)})
+	}
+	return ops
+}
+
+func TestGroupHunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		kinds string
+		want  [][2]int
+	}{
+		{"empty", "", nil},
+		{"unchanged", "     ", nil},
+		{"single change", "-", [][2]int{{0, 1}}},
+		{"context on both sides", "     -     ", [][2]int{{2, 9}}},
+		{"context clipped at the ends", " -+ ", [][2]int{{0, 4}}},
+		{"merged when the gap is twice the context", " -      +   ", [][2]int{{0, 12}}},
+		{"split when the gap is wider", " -       +    ", [][2]int{{0, 5}, {6, 13}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := script(tt.kinds)
+			hunks := groupHunks(ops)
+			if len(hunks) != len(tt.want) {
+				t.Fatalf("groupHunks() returned %d hunks, want %d", len(hunks), len(tt.want))
+			}
+			for i, hunk := range hunks {
+				start := len(ops) - cap(hunk)
+				if start != tt.want[i][0] || start+len(hunk) != tt.want[i][1] {
+					t.Errorf("hunk %d = [%d, %d), want [%d, %d)", i, start, start+len(hunk), tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	tests := []struct {
+		start  int
+		length int
+		want   string
+	}{
+		{0, 0, "0,0"},
+		{4, 0, "4,0"},
+		{0, 1, "1"},
+		{4, 1, "5"},
+		{0, 3, "1,3"},
+		{9, 2, "10,2"},
+	}
+
+	for _, tt := range tests {
+		if got := hunkRange(tt.start, tt.length); got != tt.want {
+			t.Errorf("hunkRange(%d, %d) = %q, want %q", tt.start, tt.length, got, tt.want)
+		}
+	}
+}
+
+// checkScript fails unless ops turns a into b with every op at the lines
+// it claims.
+func checkScript(t *testing.T, a, b []string, ops []op) {
+	t.Helper()
+	i, j := 0, 0
+	for _, o := range ops {
+		if o.a != i || o.b != j {
+			t.Fatalf("op %q %q at (%d, %d), want (%d, %d)", o.kind, o.line, o.a, o.b, i, j)
+		}
+		switch o.kind {
+		case opEqual:
+			if i >= len(a) || j >= len(b) || a[i] != o.line || b[j] != o.line {
+				t.Fatalf("equal op %q does not match at (%d, %d)", o.line, i, j)
+			}
+			i++
+			j++
+		case opDelete:
+			if i >= len(a) || a[i] != o.line {
+				t.Fatalf("delete op %q does not match line %d", o.line, i)
+			}
+			i++
+		case opInsert:
+			if j >= len(b) || b[j] != o.line {
+				t.Fatalf("insert op %q does not match line %d", o.line, j)
+			}
+			j++
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("script ends at (%d, %d), want (%d, %d)", i, j, len(a), len(b))
+	}
+}
+
+// lcsLength is the reference the edit scripts are checked to be minimal
+// against.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func randomLines(rnd *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + rnd.Intn(4)))
+	}
+	return lines
+}
+
+func TestLineOpsMinimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 500; round++ {
+		a := randomLines(rnd, rnd.Intn(30))
+		b := randomLines(rnd, rnd.Intn(30))
+		t.Run(fmt.Sprintf("%s/%s", strings.Join(a, ""), strings.Join(b, "")), func(t *testing.T) {
+			ops := lineOps(a, b)
+			checkScript(t, a, b, ops)
+			equal := 0
+			for _, o := range ops {
+				if o.kind == opEqual {
+					equal++
+				}
+			}
+			if want := lcsLength(a, b); equal != want {
+				t.Errorf("script keeps %d lines, want %d", equal, want)
+			}
+		})
+	}
+}
+
+func TestLineOpsLargeRewrite(t *testing.T) {
+	const lines = 50000
+	a := make([]string, lines)
+	b := make([]string, lines)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d", i)
+		b[i] = fmt.Sprintf("new %d", i)
+	}
+	a[lines/2], b[lines/2] = "same", "same"
+
+	ops := lineOps(a, b)
+	checkScript(t, a, b, ops)
+	if len(ops) != 2*lines {
+		t.Errorf("script has %d ops, want every line deleted and inserted", len(ops))
+	}
+}
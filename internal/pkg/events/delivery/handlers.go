@@ -0,0 +1,156 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/events"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/middleware"
+	"forum/internal/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	keepAliveInterval = 15 * time.Second
+	reconnectDelay    = 3 * time.Second
+)
+
+type EventDelivery struct {
+	eventUsecase events.EventUsecase
+	queryTimeout time.Duration
+	upgrader     websocket.Upgrader
+	logger       *logger.Logger
+}
+
+// NewEventDelivery takes the per-request query timeout, which streams
+// apply to their own queries, zero meaning no bound, and the origins whose
+// pages may open websockets besides the service's own host.
+func NewEventDelivery(eventUsecase events.EventUsecase, queryTimeout time.Duration, origins []string, lg *logger.Logger) *EventDelivery {
+	return &EventDelivery{
+		eventUsecase: eventUsecase,
+		queryTimeout: queryTimeout,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin(origins),
+		},
+		logger: lg,
+	}
+}
+
+func (ed *EventDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/thread/{slug_or_id}/events", ed.ThreadEventsHandler).
+		Methods(http.MethodGet, http.MethodOptions).
+		Name(middleware.StreamRoutePrefix + "thread_events")
+	r.HandleFunc("/ws", ed.WebSocketHandler).
+		Methods(http.MethodGet).
+		Name(middleware.StreamRoutePrefix + "ws")
+}
+
+// ThreadEventsHandler streams the events of a thread as Server-Sent
+// Events. A client resumes with the Last-Event-ID header, or with the
+// lastEventId query parameter where it cannot set headers.
+func (ed *EventDelivery) ThreadEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Error(w, r, errors.New("response writer does not support flushing"))
+		return
+	}
+
+	slug := mux.Vars(r)["slug_or_id"]
+	id, err := strconv.ParseInt(slug, 10, 64)
+	if err == nil {
+		slug = ""
+	} else {
+		id = 0
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	var lastId int64
+	if lastEventId != "" {
+		lastId, err = strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil {
+			response.Error(w, r, myerr.InvalidCursor.WithMessage("Last-Event-ID %q is not an event id", lastEventId))
+			return
+		}
+	}
+
+	queryCtx, cancel := ed.queryContext(r.Context())
+	sub, replay, err := ed.eventUsecase.Subscribe(queryCtx, slug, id, lastId)
+	cancel()
+	switch {
+	case err == nil:
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread {slug: '%s', id: %d} not found", slug, id))
+		return
+	default:
+		response.Error(w, r, err)
+		return
+	}
+	defer ed.eventUsecase.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds()); err != nil {
+		return
+	}
+	for _, event := range replay {
+		if err = writeEvent(w, event); err != nil {
+			return
+		}
+		lastId = event.Id
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			// the replay and the subscription overlap
+			if event.Id <= lastId {
+				continue
+			}
+			err = writeEvent(w, event)
+			lastId = event.Id
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func (ed *EventDelivery) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ed.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, ed.queryTimeout)
+}
+
+func writeEvent(w http.ResponseWriter, event *models.ThreadEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Kind, event.Payload)
+	return err
+}
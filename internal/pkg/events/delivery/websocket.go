@@ -0,0 +1,251 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/events/hub"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait     = 10 * time.Second
+	wsPongWait      = 60 * time.Second
+	wsPingInterval  = wsPongWait * 9 / 10
+	wsMaxMessage    = 4096
+	wsMaxTopics     = 100
+	wsRepliesBuffer = 16
+)
+
+// Types of websocket messages. Clients send subscribe and unsubscribe,
+// the server answers with the rest.
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsEvent        = "event"
+	wsError        = "error"
+)
+
+// checkOrigin lets clients that send no Origin, which browsers always do,
+// pages of the service's own host and pages of the listed origins upgrade.
+// "*" lets any origin upgrade.
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.ToLower(origin)] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed["*"] || allowed[strings.ToLower(origin)] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// wsRequest names either a forum slug or a thread slug_or_id, which may be
+// sent as a string or a number.
+type wsRequest struct {
+	Type   string          `json:"type"`
+	Forum  string          `json:"forum,omitempty"`
+	Thread json.RawMessage `json:"thread,omitempty"`
+}
+
+type wsMessage struct {
+	Type   string              `json:"type"`
+	Forum  string              `json:"forum,omitempty"`
+	Thread int64               `json:"thread,omitempty"`
+	Event  *models.ThreadEvent `json:"event,omitempty"`
+	Error  *models.Error       `json:"error,omitempty"`
+}
+
+// WebSocketHandler serves one socket that may follow several forums and
+// threads. A client too slow to keep up is disconnected and resumes by
+// subscribing again.
+func (ed *EventDelivery) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := ed.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered
+		return
+	}
+	defer conn.Close()
+
+	sub := ed.eventUsecase.Open()
+	defer ed.eventUsecase.Unsubscribe(sub)
+
+	replies := make(chan *wsMessage, wsRepliesBuffer)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		ed.readRequests(r, conn, sub, replies)
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-readerDone:
+			return
+		case <-r.Context().Done():
+			return
+		case reply := <-replies:
+			err = writeMessage(conn, reply)
+		case event, ok := <-sub.Events():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscription dropped, reconnect"),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+			err = writeMessage(conn, &wsMessage{Type: wsEvent, Event: event})
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readRequests handles the client's messages until the socket fails or
+// the client stops answering pings.
+func (ed *EventDelivery) readRequests(r *http.Request, conn *websocket.Conn, sub *hub.Subscription, replies chan<- *wsMessage) {
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	topics := make(map[string]struct{})
+	for {
+		_, buf, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				ed.logger.Warn(r.Context(), "websocket read failed", "error", err)
+			}
+			return
+		}
+
+		reply := ed.handleRequest(r, sub, topics, buf)
+		select {
+		case replies <- reply:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (ed *EventDelivery) handleRequest(r *http.Request, sub *hub.Subscription, topics map[string]struct{}, buf []byte) *wsMessage {
+	req := &wsRequest{}
+	if err := json.Unmarshal(buf, req); err != nil {
+		return ed.errorMessage(r.Context(), myerr.InvalidBody)
+	}
+	if req.Type != wsSubscribe && req.Type != wsUnsubscribe {
+		return ed.errorMessage(r.Context(), myerr.InvalidBody.WithMessage("type must be %q or %q", wsSubscribe, wsUnsubscribe))
+	}
+	if req.Type == wsSubscribe && len(topics) >= wsMaxTopics {
+		return ed.errorMessage(r.Context(), myerr.InvalidBody.WithMessage("at most %d subscriptions per socket", wsMaxTopics))
+	}
+
+	ctx, cancel := ed.queryContext(r.Context())
+	defer cancel()
+
+	switch {
+	case req.Forum != "" && len(req.Thread) == 0:
+		var slug string
+		var err error
+		if req.Type == wsSubscribe {
+			slug, err = ed.eventUsecase.FollowForum(ctx, sub, req.Forum)
+		} else {
+			slug, err = ed.eventUsecase.UnfollowForum(ctx, sub, req.Forum)
+		}
+		if err != nil {
+			return ed.errorMessage(r.Context(), err)
+		}
+		updateTopics(topics, hub.ForumTopic(slug), req.Type)
+		return &wsMessage{Type: replyType(req.Type), Forum: slug}
+	case req.Forum == "" && len(req.Thread) != 0:
+		slug, id, ok := parseSlugOrId(req.Thread)
+		if !ok {
+			return ed.errorMessage(r.Context(), myerr.InvalidBody.WithMessage("thread must be a slug or an id"))
+		}
+		var threadId int64
+		var err error
+		if req.Type == wsSubscribe {
+			threadId, err = ed.eventUsecase.FollowThread(ctx, sub, slug, id)
+		} else {
+			threadId, err = ed.eventUsecase.UnfollowThread(ctx, sub, slug, id)
+		}
+		if err != nil {
+			return ed.errorMessage(r.Context(), err)
+		}
+		updateTopics(topics, hub.ThreadTopic(threadId), req.Type)
+		return &wsMessage{Type: replyType(req.Type), Thread: threadId}
+	default:
+		return ed.errorMessage(r.Context(), myerr.InvalidBody.WithMessage("exactly one of forum and thread is required"))
+	}
+}
+
+func replyType(action string) string {
+	if action == wsSubscribe {
+		return wsSubscribed
+	}
+	return wsUnsubscribed
+}
+
+func updateTopics(topics map[string]struct{}, topic string, action string) {
+	if action == wsSubscribe {
+		topics[topic] = struct{}{}
+	} else {
+		delete(topics, topic)
+	}
+}
+
+func parseSlugOrId(raw json.RawMessage) (string, int64, bool) {
+	var slugOrId string
+	if bytes.HasPrefix(raw, []byte(`"`)) {
+		if err := json.Unmarshal(raw, &slugOrId); err != nil {
+			return "", 0, false
+		}
+	} else {
+		slugOrId = string(raw)
+	}
+	if slugOrId == "" {
+		return "", 0, false
+	}
+
+	id, err := strconv.ParseInt(slugOrId, 10, 64)
+	if err == nil {
+		return "", id, true
+	}
+	return slugOrId, 0, true
+}
+
+// errorMessage renders err the way response.Error does for HTTP.
+func (ed *EventDelivery) errorMessage(ctx context.Context, err error) *wsMessage {
+	var ce myerr.CustomError
+	if !errors.As(err, &ce) || (ce.Code >= http.StatusInternalServerError &&
+		ce.Code != http.StatusServiceUnavailable && ce.Code != http.StatusGatewayTimeout) {
+		ed.logger.Error(ctx, "websocket request failed", "error", err)
+		return &wsMessage{Type: wsError, Error: &models.Error{Code: "internal_error", Message: "internal server error"}}
+	}
+	return &wsMessage{Type: wsError, Error: &models.Error{Code: ce.Reason, Message: ce.Message}}
+}
+
+func writeMessage(conn *websocket.Conn, msg *wsMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(msg)
+}
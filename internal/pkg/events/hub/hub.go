@@ -0,0 +1,326 @@
+// Package hub fans thread events announced by Postgres NOTIFY out to the
+// subscribers connected to this instance.
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+const (
+	channel = "thread_events"
+
+	// subscriptionBuffer is how far a subscriber may fall behind before
+	// it is dropped and has to resume with Last-Event-ID.
+	subscriptionBuffer = 64
+
+	fetchTimeout      = 5 * time.Second
+	maxListenBackoff  = 30 * time.Second
+	minPruneInterval  = time.Minute
+	pruneQueryTimeout = time.Minute
+)
+
+// Store is the part of the event repository the hub reads from.
+type Store interface {
+	SelectEvent(ctx context.Context, id int64) (*models.ThreadEvent, error)
+	SelectEventsAfter(ctx context.Context, lastId int64) ([]*models.ThreadEvent, error)
+	DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ThreadTopic and ForumTopic name what a subscription follows.
+func ThreadTopic(id int64) string {
+	return "thread:" + strconv.FormatInt(id, 10)
+}
+
+// ForumTopic folds case as forum slugs are case insensitive.
+func ForumTopic(slug string) string {
+	return "forum:" + strings.ToLower(slug)
+}
+
+// Subscription receives the events of its topics until it is unsubscribed
+// or dropped, which closes Events. An event matching several topics of one
+// subscription arrives once.
+type Subscription struct {
+	events chan *models.ThreadEvent
+	topics map[string]struct{}
+}
+
+func (s *Subscription) Events() <-chan *models.ThreadEvent {
+	return s.events
+}
+
+type Hub struct {
+	connConfig pgx.ConnConfig
+	store      Store
+	retention  time.Duration
+	logger     *logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool
+	lastId      int64
+}
+
+// NewHub listens with its own connection to dsn, outside the pool.
+// Events older than retention are pruned; zero keeps them forever.
+func NewHub(dsn string, store Store, retention time.Duration, lg *logger.Logger) (*Hub, error) {
+	connConfig, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hub{
+		connConfig:  connConfig,
+		store:       store,
+		retention:   retention,
+		logger:      lg,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}, nil
+}
+
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		events: make(chan *models.ThreadEvent, subscriptionBuffer),
+		topics: make(map[string]struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.topics = nil
+		close(sub.events)
+		return sub
+	}
+	for _, topic := range topics {
+		h.follow(sub, topic)
+	}
+	return sub
+}
+
+// Follow adds a topic to a live subscription. It reports false once the
+// subscription was dropped.
+func (h *Hub) Follow(sub *Subscription, topic string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub.topics == nil {
+		return false
+	}
+	h.follow(sub, topic)
+	return true
+}
+
+func (h *Hub) Unfollow(sub *Subscription, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub.topics == nil {
+		return
+	}
+	h.unfollow(sub, topic)
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(sub)
+}
+
+// follow, unfollow and drop must be called with mu held.
+func (h *Hub) follow(sub *Subscription, topic string) {
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[topic][sub] = struct{}{}
+	sub.topics[topic] = struct{}{}
+}
+
+func (h *Hub) unfollow(sub *Subscription, topic string) {
+	delete(sub.topics, topic)
+	subs := h.subscribers[topic]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, topic)
+	}
+}
+
+func (h *Hub) drop(sub *Subscription) {
+	if sub.topics == nil {
+		return
+	}
+	for topic := range sub.topics {
+		h.unfollow(sub, topic)
+	}
+	sub.topics = nil
+	close(sub.events)
+}
+
+// Run listens until ctx is done, reconnecting after failures, and then
+// closes every subscription so open streams end.
+func (h *Hub) Run(ctx context.Context) {
+	if h.retention > 0 {
+		go h.pruneLoop(ctx)
+	}
+
+	backoff := time.Second
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		h.logger.Error(ctx, "event listener failed", "error", err, "reconnect_in", backoff)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if backoff *= 2; backoff > maxListenBackoff {
+			backoff = maxListenBackoff
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.drop(sub)
+		}
+	}
+}
+
+func (h *Hub) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(h.connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = conn.Listen(channel); err != nil {
+		return err
+	}
+	h.catchUp(ctx)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		h.notify(ctx, notification.Payload)
+	}
+}
+
+// catchUp delivers what was emitted while the hub was not listening.
+func (h *Hub) catchUp(ctx context.Context) {
+	h.mu.Lock()
+	lastId := h.lastId
+	h.mu.Unlock()
+	if lastId == 0 {
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	events, err := h.store.SelectEventsAfter(fetchCtx, lastId)
+	if err != nil {
+		h.logger.Error(ctx, "event catch up failed", "error", err)
+		return
+	}
+	for _, event := range events {
+		h.publish(event)
+	}
+}
+
+func (h *Hub) notify(ctx context.Context, payload string) {
+	announced := struct {
+		Id     int64  `json:"id"`
+		Thread int64  `json:"thread"`
+		Forum  string `json:"forum"`
+	}{}
+	if err := json.Unmarshal([]byte(payload), &announced); err != nil {
+		h.logger.Error(ctx, "event notification is malformed", "payload", payload, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	if announced.Id > h.lastId {
+		h.lastId = announced.Id
+	}
+	_, watched := h.subscribers[ThreadTopic(announced.Thread)]
+	if !watched {
+		_, watched = h.subscribers[ForumTopic(announced.Forum)]
+	}
+	h.mu.Unlock()
+	if !watched {
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	event, err := h.store.SelectEvent(fetchCtx, announced.Id)
+	if err != nil {
+		h.logger.Error(ctx, "event fetch failed", "event", announced.Id, "error", err)
+		return
+	}
+	h.publish(event)
+}
+
+// publish never blocks: a subscriber whose buffer is full is dropped.
+// Events of hidden threads reach only the thread's own subscribers.
+func (h *Hub) publish(event *models.ThreadEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if event.Id > h.lastId {
+		h.lastId = event.Id
+	}
+	topics := []string{ThreadTopic(event.Thread)}
+	if !event.Hidden {
+		topics = append(topics, ForumTopic(event.Forum))
+	}
+	receivers := make(map[*Subscription]struct{})
+	for _, topic := range topics {
+		for sub := range h.subscribers[topic] {
+			receivers[sub] = struct{}{}
+		}
+	}
+	for sub := range receivers {
+		select {
+		case sub.events <- event:
+		default:
+			h.drop(sub)
+		}
+	}
+}
+
+func (h *Hub) pruneLoop(ctx context.Context) {
+	interval := h.retention / 10
+	if interval < minPruneInterval {
+		interval = minPruneInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pruneCtx, cancel := context.WithTimeout(ctx, pruneQueryTimeout)
+		_, err := h.store.DeleteEventsBefore(pruneCtx, time.Now().Add(-h.retention))
+		cancel()
+		if err != nil {
+			h.logger.Error(ctx, "event prune failed", "error", err)
+		}
+	}
+}
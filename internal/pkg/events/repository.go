@@ -0,0 +1,16 @@
+package events
+
+import (
+	"context"
+	"forum/internal/models"
+	"time"
+)
+
+type EventRepository interface {
+	SelectThread(ctx context.Context, slug string, id int64) (int64, error)
+	SelectForum(ctx context.Context, slug string) (string, error)
+	SelectEvent(ctx context.Context, id int64) (*models.ThreadEvent, error)
+	SelectEventsSince(ctx context.Context, thread int64, lastId int64) ([]*models.ThreadEvent, error)
+	SelectEventsAfter(ctx context.Context, lastId int64) ([]*models.ThreadEvent, error)
+	DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error)
+}
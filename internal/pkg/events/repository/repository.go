@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/events"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"time"
+)
+
+// replayLimit bounds the events a resuming client is sent at once.
+const replayLimit = 1000
+
+type EventRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewEventRepository(db *sql.DB, lg *logger.Logger) events.EventRepository {
+	return &EventRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+func (er *EventRepository) SelectThread(ctx context.Context, slug string, id int64) (int64, error) {
+	defer metrics.ObserveQuery("events", "SelectThread", time.Now())
+
+	row := er.db.QueryRowContext(
+		ctx,
+		"SELECT id from threads WHERE (0 = $1 AND slug = $2 OR $2 = '' AND id = $1) AND status <> 'hidden'",
+		id, slug)
+	err := row.Scan(&id)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			er.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+	return id, nil
+}
+
+func (er *EventRepository) SelectForum(ctx context.Context, slug string) (string, error) {
+	defer metrics.ObserveQuery("events", "SelectForum", time.Now())
+
+	row := er.db.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1", slug)
+	err := row.Scan(&slug)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			er.logger.Error(ctx, err.Error())
+		}
+		return "", dbErr
+	}
+	return slug, nil
+}
+
+// eventColumns is what events are scanned from, joined with their thread t.
+const eventColumns = "e.id, e.thread, e.forum, e.kind, e.payload, t.status = 'hidden'"
+
+func (er *EventRepository) SelectEvent(ctx context.Context, id int64) (*models.ThreadEvent, error) {
+	defer metrics.ObserveQuery("events", "SelectEvent", time.Now())
+
+	event := &models.ThreadEvent{}
+	var payload []byte
+	row := er.db.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM thread_events e JOIN threads t ON t.id = e.thread WHERE e.id = $1;", id)
+	err := row.Scan(&event.Id, &event.Thread, &event.Forum, &event.Kind, &payload, &event.Hidden)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			er.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	event.Payload = payload
+	return event, nil
+}
+
+func (er *EventRepository) SelectEventsSince(ctx context.Context, thread int64, lastId int64) ([]*models.ThreadEvent, error) {
+	defer metrics.ObserveQuery("events", "SelectEventsSince", time.Now())
+
+	return er.selectEvents(
+		ctx,
+		"SELECT "+eventColumns+" FROM thread_events e JOIN threads t ON t.id = e.thread WHERE e.thread = $1 AND e.id > $2 ORDER BY e.id LIMIT $3;",
+		thread, lastId, replayLimit)
+}
+
+func (er *EventRepository) SelectEventsAfter(ctx context.Context, lastId int64) ([]*models.ThreadEvent, error) {
+	defer metrics.ObserveQuery("events", "SelectEventsAfter", time.Now())
+
+	return er.selectEvents(
+		ctx,
+		"SELECT "+eventColumns+" FROM thread_events e JOIN threads t ON t.id = e.thread WHERE e.id > $1 ORDER BY e.id LIMIT $2;",
+		lastId, replayLimit)
+}
+
+func (er *EventRepository) selectEvents(ctx context.Context, query string, args ...interface{}) ([]*models.ThreadEvent, error) {
+	rows, err := er.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			er.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	events := make([]*models.ThreadEvent, 0)
+	for rows.Next() {
+		event := &models.ThreadEvent{}
+		var payload []byte
+		err = rows.Scan(&event.Id, &event.Thread, &event.Forum, &event.Kind, &payload, &event.Hidden)
+		if err != nil {
+			er.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (er *EventRepository) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("events", "DeleteEventsBefore", time.Now())
+
+	res, err := er.db.ExecContext(ctx, "DELETE FROM thread_events WHERE created < $1;", before)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			er.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+	deleted, _ := res.RowsAffected()
+	return deleted, nil
+}
@@ -0,0 +1,17 @@
+package events
+
+import (
+	"context"
+	"forum/internal/models"
+	"forum/internal/pkg/events/hub"
+)
+
+type EventUsecase interface {
+	Subscribe(ctx context.Context, slug string, id int64, lastEventId int64) (*hub.Subscription, []*models.ThreadEvent, error)
+	Unsubscribe(sub *hub.Subscription)
+	Open() *hub.Subscription
+	FollowThread(ctx context.Context, sub *hub.Subscription, slug string, id int64) (int64, error)
+	UnfollowThread(ctx context.Context, sub *hub.Subscription, slug string, id int64) (int64, error)
+	FollowForum(ctx context.Context, sub *hub.Subscription, slug string) (string, error)
+	UnfollowForum(ctx context.Context, sub *hub.Subscription, slug string) (string, error)
+}
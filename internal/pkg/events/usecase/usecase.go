@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/events"
+	"forum/internal/pkg/events/hub"
+)
+
+type EventUsecase struct {
+	repo events.EventRepository
+	hub  *hub.Hub
+}
+
+func NewEventUsecase(repo events.EventRepository, hub *hub.Hub) events.EventUsecase {
+	return &EventUsecase{
+		repo: repo,
+		hub:  hub,
+	}
+}
+
+// Subscribe starts following a thread and, for a resuming client, returns
+// what it missed after lastEventId. The subscription is taken before the
+// replay is read, so events may arrive twice but never go missing.
+func (eu *EventUsecase) Subscribe(ctx context.Context, slug string, id int64, lastEventId int64) (*hub.Subscription, []*models.ThreadEvent, error) {
+	threadId, err := eu.repo.SelectThread(ctx, slug, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := eu.hub.Subscribe(hub.ThreadTopic(threadId))
+	if lastEventId <= 0 {
+		return sub, make([]*models.ThreadEvent, 0), nil
+	}
+
+	replay, err := eu.repo.SelectEventsSince(ctx, threadId, lastEventId)
+	if err != nil {
+		eu.hub.Unsubscribe(sub)
+		return nil, nil, err
+	}
+	return sub, replay, nil
+}
+
+func (eu *EventUsecase) Unsubscribe(sub *hub.Subscription) {
+	eu.hub.Unsubscribe(sub)
+}
+
+// Open returns a subscription that follows nothing yet.
+func (eu *EventUsecase) Open() *hub.Subscription {
+	return eu.hub.Subscribe()
+}
+
+func (eu *EventUsecase) FollowThread(ctx context.Context, sub *hub.Subscription, slug string, id int64) (int64, error) {
+	threadId, err := eu.repo.SelectThread(ctx, slug, id)
+	if err != nil {
+		return 0, err
+	}
+	if !eu.hub.Follow(sub, hub.ThreadTopic(threadId)) {
+		return 0, myerr.RequestCanceled
+	}
+	return threadId, nil
+}
+
+func (eu *EventUsecase) UnfollowThread(ctx context.Context, sub *hub.Subscription, slug string, id int64) (int64, error) {
+	threadId, err := eu.repo.SelectThread(ctx, slug, id)
+	if err != nil {
+		return 0, err
+	}
+	eu.hub.Unfollow(sub, hub.ThreadTopic(threadId))
+	return threadId, nil
+}
+
+func (eu *EventUsecase) FollowForum(ctx context.Context, sub *hub.Subscription, slug string) (string, error) {
+	forumSlug, err := eu.repo.SelectForum(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+	if !eu.hub.Follow(sub, hub.ForumTopic(forumSlug)) {
+		return "", myerr.RequestCanceled
+	}
+	return forumSlug, nil
+}
+
+// UnfollowForum needs no lookup, forum topics are keyed by the folded slug.
+func (eu *EventUsecase) UnfollowForum(ctx context.Context, sub *hub.Subscription, slug string) (string, error) {
+	eu.hub.Unfollow(sub, hub.ForumTopic(slug))
+	return slug, nil
+}
@@ -0,0 +1,147 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/filters"
+	"forum/internal/pkg/posts"
+	"forum/internal/pkg/response"
+	"forum/internal/pkg/threads"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// FilterDelivery publishes approved content through the post and thread
+// usecases, so it goes through the same checks as when it was submitted.
+type FilterDelivery struct {
+	filterUsecase filters.FilterUsecase
+	postUsecase   posts.PostUsecase
+	threadUsecase threads.ThreadUsecase
+}
+
+func NewFilterDelivery(filterUsecase filters.FilterUsecase, postUsecase posts.PostUsecase, threadUsecase threads.ThreadUsecase) *FilterDelivery {
+	return &FilterDelivery{
+		filterUsecase: filterUsecase,
+		postUsecase:   postUsecase,
+		threadUsecase: threadUsecase,
+	}
+}
+
+func (fd *FilterDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/forum/{slug}/filters", fd.GetSettingsHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/filters", fd.UpdateSettingsHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/held", fd.GetHeldHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/held/{id:[0-9]+}/approve", fd.ApproveHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/held/{id:[0-9]+}", fd.DropHandler).Methods(http.MethodDelete, http.MethodOptions)
+}
+
+func (fd *FilterDelivery) GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	settings, err := fd.filterUsecase.GetSettings(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, settings)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+// UpdateSettingsHandler replaces the settings; omitted fields take their
+// defaults.
+func (fd *FilterDelivery) UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	settings := models.DefaultFilterSettings(slug)
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	err = json.Unmarshal(buf, settings)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+	settings.Forum = slug
+
+	settings, err = fd.filterUsecase.UpdateSettings(r.Context(), settings)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, settings)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (fd *FilterDelivery) GetHeldHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	held, err := fd.filterUsecase.GetHeld(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, held)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+// ApproveHandler publishes the held content and answers like its create
+// request would have. The content stays held when publishing fails.
+func (fd *FilterDelivery) ApproveHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	held, err := fd.filterUsecase.GetHeldItem(r.Context(), slug, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := filters.Approved(r.Context())
+	var published interface{}
+	switch held.Kind {
+	case models.HeldPosts:
+		postsInput := []*models.PostInput{}
+		err = json.Unmarshal(held.Payload, &postsInput)
+		if err == nil {
+			published, err = fd.postUsecase.CreatePostsBySlugOrId(ctx, "", held.Thread, postsInput)
+		}
+	case models.HeldThread:
+		thread := &models.Thread{}
+		err = json.Unmarshal(held.Payload, thread)
+		if err == nil {
+			published, err = fd.threadUsecase.CreateThread(ctx, thread)
+		}
+	}
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = fd.filterUsecase.DropHeld(r.Context(), slug, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, published)
+}
+
+func (fd *FilterDelivery) DropHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	err := fd.filterUsecase.DropHeld(r.Context(), mux.Vars(r)["slug"], id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,15 @@
+package filters
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type FilterRepository interface {
+	SelectSettings(ctx context.Context, forum string) (*models.FilterSettings, error)
+	UpdateSettings(ctx context.Context, settings *models.FilterSettings) error
+	InsertHeld(ctx context.Context, held *models.HeldContent) error
+	SelectHeld(ctx context.Context, forum string) ([]*models.HeldContent, error)
+	SelectHeldItem(ctx context.Context, forum string, id int64) (*models.HeldContent, error)
+	DeleteHeld(ctx context.Context, forum string, id int64) error
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/filters"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type FilterRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewFilterRepository(db *sql.DB, lg *logger.Logger) filters.FilterRepository {
+	return &FilterRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+// SelectSettings falls back to the defaults for a forum nobody configured.
+func (fr *FilterRepository) SelectSettings(ctx context.Context, forum string) (*models.FilterSettings, error) {
+	defer metrics.ObserveQuery("filters", "SelectSettings", time.Now())
+
+	settings := models.DefaultFilterSettings(forum)
+	row := fr.db.QueryRowContext(
+		ctx,
+		`SELECT f.slug,
+			COALESCE(ff.bannedWords, '{}'), COALESCE(ff.allowedWords, '{}'), COALESCE(ff.bannedWordsAction, $2),
+			COALESCE(ff.maxLength, 0), COALESCE(ff.maxLengthAction, $3),
+			COALESCE(ff.maxLinks, 0), COALESCE(ff.maxLinksAction, $4),
+			COALESCE(ff.shouting, FALSE), COALESCE(ff.shoutingAction, $5)
+		 FROM forum f
+		 LEFT JOIN forum_filters ff ON ff.forum = f.slug
+		 WHERE f.slug = $1;`,
+		forum, settings.BannedWordsAction, settings.MaxLengthAction, settings.MaxLinksAction, settings.ShoutingAction)
+	err := row.Scan(&settings.Forum,
+		pq.Array(&settings.BannedWords), pq.Array(&settings.AllowedWords), &settings.BannedWordsAction,
+		&settings.MaxLength, &settings.MaxLengthAction,
+		&settings.MaxLinks, &settings.MaxLinksAction,
+		&settings.Shouting, &settings.ShoutingAction)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	return settings, nil
+}
+
+func (fr *FilterRepository) UpdateSettings(ctx context.Context, settings *models.FilterSettings) error {
+	defer metrics.ObserveQuery("filters", "UpdateSettings", time.Now())
+
+	row := fr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO forum_filters (forum, bannedWords, allowedWords, bannedWordsAction, maxLength, maxLengthAction,
+			maxLinks, maxLinksAction, shouting, shoutingAction)
+		 VALUES (COALESCE((SELECT slug FROM forum WHERE slug = $1), $1), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (forum) DO UPDATE SET
+			bannedWords = EXCLUDED.bannedWords,
+			allowedWords = EXCLUDED.allowedWords,
+			bannedWordsAction = EXCLUDED.bannedWordsAction,
+			maxLength = EXCLUDED.maxLength,
+			maxLengthAction = EXCLUDED.maxLengthAction,
+			maxLinks = EXCLUDED.maxLinks,
+			maxLinksAction = EXCLUDED.maxLinksAction,
+			shouting = EXCLUDED.shouting,
+			shoutingAction = EXCLUDED.shoutingAction
+		 RETURNING forum;`,
+		settings.Forum, pq.Array(settings.BannedWords), pq.Array(settings.AllowedWords), settings.BannedWordsAction,
+		settings.MaxLength, settings.MaxLengthAction, settings.MaxLinks, settings.MaxLinksAction,
+		settings.Shouting, settings.ShoutingAction)
+	err := row.Scan(&settings.Forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (fr *FilterRepository) InsertHeld(ctx context.Context, held *models.HeldContent) error {
+	defer metrics.ObserveQuery("filters", "InsertHeld", time.Now())
+
+	row := fr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO held_content (kind, forum, thread, author, rule, payload)
+		 VALUES ($1, COALESCE((SELECT slug FROM forum WHERE slug = $2), $2), NULLIF($3, 0), $4, $5, $6)
+		 RETURNING id, forum, created;`,
+		held.Kind, held.Forum, held.Thread, held.Author, held.Rule, string(held.Payload))
+	err := row.Scan(&held.Id, &held.Forum, &held.Created)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (fr *FilterRepository) SelectHeld(ctx context.Context, forum string) ([]*models.HeldContent, error) {
+	defer metrics.ObserveQuery("filters", "SelectHeld", time.Now())
+
+	row := fr.db.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1;", forum)
+	err := row.Scan(&forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	rows, err := fr.db.QueryContext(
+		ctx,
+		`SELECT id, kind, forum, COALESCE(thread, 0), author, rule, payload, created FROM held_content
+		 WHERE forum = $1
+		 ORDER BY id;`,
+		forum)
+	if err != nil {
+		fr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	held := make([]*models.HeldContent, 0)
+	for rows.Next() {
+		item := &models.HeldContent{}
+		var payload []byte
+		err = rows.Scan(&item.Id, &item.Kind, &item.Forum, &item.Thread, &item.Author, &item.Rule, &payload, &item.Created)
+		if err != nil {
+			fr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		item.Payload = payload
+		held = append(held, item)
+	}
+	return held, nil
+}
+
+func (fr *FilterRepository) SelectHeldItem(ctx context.Context, forum string, id int64) (*models.HeldContent, error) {
+	defer metrics.ObserveQuery("filters", "SelectHeldItem", time.Now())
+
+	item := &models.HeldContent{}
+	var payload []byte
+	row := fr.db.QueryRowContext(
+		ctx,
+		`SELECT id, kind, forum, COALESCE(thread, 0), author, rule, payload, created FROM held_content
+		 WHERE id = $1 AND forum = $2;`,
+		id, forum)
+	err := row.Scan(&item.Id, &item.Kind, &item.Forum, &item.Thread, &item.Author, &item.Rule, &payload, &item.Created)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.HeldContentNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	item.Payload = payload
+	return item, nil
+}
+
+func (fr *FilterRepository) DeleteHeld(ctx context.Context, forum string, id int64) error {
+	defer metrics.ObserveQuery("filters", "DeleteHeld", time.Now())
+
+	res, err := fr.db.ExecContext(ctx, "DELETE FROM held_content WHERE id = $1 AND forum = $2;", id, forum)
+	if err != nil {
+		fr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		fr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	if deleted == 0 {
+		return myerr.HeldContentNotExist
+	}
+	return nil
+}
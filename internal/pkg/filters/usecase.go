@@ -0,0 +1,34 @@
+package filters
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+// Content is what the filters screen. Texts are masked in place, Authors
+// tells who wrote each of them and Payload is kept when a rule holds the
+// content for review.
+type Content struct {
+	Kind    string
+	Forum   string
+	Thread  int64
+	Texts   []*string
+	Authors []string
+	Payload interface{}
+}
+
+// Screener runs the filter pipeline of a forum over new content. It
+// answers ContentRejected naming the rule that fired, or ContentHeld once
+// the content is queued for the moderators.
+type Screener interface {
+	Screen(ctx context.Context, content *Content) error
+}
+
+type FilterUsecase interface {
+	Screener
+	GetSettings(ctx context.Context, forum string) (*models.FilterSettings, error)
+	UpdateSettings(ctx context.Context, settings *models.FilterSettings) (*models.FilterSettings, error)
+	GetHeld(ctx context.Context, forum string) ([]*models.HeldContent, error)
+	GetHeldItem(ctx context.Context, forum string, id int64) (*models.HeldContent, error)
+	DropHeld(ctx context.Context, forum string, id int64) error
+}
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/pkg/filters"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linkPattern matches what clients render as a link.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+const (
+	// shoutingLetters is the least number of letters a text needs before
+	// its case counts as shouting.
+	shoutingLetters = 12
+	// shoutingRun is the shortest run of one character taken for shouting.
+	shoutingRun = 6
+)
+
+// newPipeline builds the built-in filters the settings turn on.
+func newPipeline(settings *models.FilterSettings, siteWords []string) []filters.Filter {
+	pipeline := make([]filters.Filter, 0, 4)
+	if words := bannedWordList(siteWords, settings); len(words) != 0 {
+		pipeline = append(pipeline, newBannedWords(words, settings.BannedWordsAction))
+	}
+	if settings.MaxLength > 0 {
+		pipeline = append(pipeline, &maxLength{limit: settings.MaxLength, action: settings.MaxLengthAction})
+	}
+	if settings.MaxLinks > 0 {
+		pipeline = append(pipeline, &maxLinks{limit: settings.MaxLinks, action: settings.MaxLinksAction})
+	}
+	if settings.Shouting {
+		pipeline = append(pipeline, &shouting{action: settings.ShoutingAction})
+	}
+	return pipeline
+}
+
+// bannedWordList merges the site-wide list with the forum's own words and
+// drops the words the forum allows.
+func bannedWordList(siteWords []string, settings *models.FilterSettings) []string {
+	allowed := make(map[string]bool, len(settings.AllowedWords))
+	for _, word := range settings.AllowedWords {
+		allowed[strings.ToLower(word)] = true
+	}
+
+	words := make([]string, 0, len(siteWords)+len(settings.BannedWords))
+	seen := make(map[string]bool)
+	for _, list := range [][]string{siteWords, settings.BannedWords} {
+		for _, word := range list {
+			key := strings.ToLower(strings.TrimSpace(word))
+			if key == "" || allowed[key] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			words = append(words, key)
+		}
+	}
+	return words
+}
+
+type bannedWords struct {
+	pattern *regexp.Regexp
+	action  string
+}
+
+func newBannedWords(words []string, action string) *bannedWords {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	return &bannedWords{
+		pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		action:  action,
+	}
+}
+
+func (bw *bannedWords) Rule() string   { return models.RuleBannedWords }
+func (bw *bannedWords) Action() string { return bw.action }
+
+func (bw *bannedWords) Apply(text string) (string, string, bool) {
+	found := bw.pattern.FindString(text)
+	if found == "" {
+		return text, "", false
+	}
+	masked := bw.pattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	})
+	return masked, fmt.Sprintf("the word %q is banned", found), true
+}
+
+// maxLength masks by cutting the text down to the limit.
+type maxLength struct {
+	limit  int
+	action string
+}
+
+func (ml *maxLength) Rule() string   { return models.RuleMaxLength }
+func (ml *maxLength) Action() string { return ml.action }
+
+func (ml *maxLength) Apply(text string) (string, string, bool) {
+	length := utf8.RuneCountInString(text)
+	if length <= ml.limit {
+		return text, "", false
+	}
+	return string([]rune(text)[:ml.limit]), fmt.Sprintf("%d characters exceed the limit of %d", length, ml.limit), true
+}
+
+// maxLinks masks by removing the links past the limit.
+type maxLinks struct {
+	limit  int
+	action string
+}
+
+func (ml *maxLinks) Rule() string   { return models.RuleMaxLinks }
+func (ml *maxLinks) Action() string { return ml.action }
+
+func (ml *maxLinks) Apply(text string) (string, string, bool) {
+	count := len(linkPattern.FindAllStringIndex(text, -1))
+	if count <= ml.limit {
+		return text, "", false
+	}
+
+	seen := 0
+	masked := linkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		seen++
+		if seen <= ml.limit {
+			return link
+		}
+		return "[link removed]"
+	})
+	return masked, fmt.Sprintf("%d links exceed the limit of %d", count, ml.limit), true
+}
+
+// shouting catches texts written in capitals and long runs of a single
+// character. It masks by lowering the case and shortening the runs.
+type shouting struct {
+	action string
+}
+
+func (s *shouting) Rule() string   { return models.RuleShouting }
+func (s *shouting) Action() string { return s.action }
+
+func (s *shouting) Apply(text string) (string, string, bool) {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	capitals := letters >= shoutingLetters && upper*10 >= letters*7
+
+	collapsed, repeated := collapseRuns(text)
+	if !capitals && !repeated {
+		return text, "", false
+	}
+
+	detail := "text repeats a character too many times"
+	if capitals {
+		detail = "text is written in capitals"
+		collapsed = strings.ToLower(collapsed)
+	}
+	return collapsed, detail, true
+}
+
+// collapseRuns shortens runs of shoutingRun or more equal characters to
+// three and tells whether there were any.
+func collapseRuns(text string) (string, bool) {
+	var b strings.Builder
+	found := false
+	run := 0
+	var last rune
+	for i, r := range text {
+		if i > 0 && r == last {
+			run++
+		} else {
+			run = 1
+			last = r
+		}
+		if run == shoutingRun {
+			found = true
+		}
+		if run <= 3 {
+			b.WriteRune(r)
+		}
+	}
+	if !found {
+		return text, false
+	}
+	return b.String(), true
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/filters"
+)
+
+type FilterUsecase struct {
+	repo      filters.FilterRepository
+	roles     auth.RoleResolver
+	siteWords []string
+}
+
+// NewFilterUsecase screens content against the forum's settings and the
+// site-wide banned words.
+func NewFilterUsecase(repo filters.FilterRepository, roles auth.RoleResolver, siteWords []string) filters.FilterUsecase {
+	return &FilterUsecase{
+		repo:      repo,
+		roles:     roles,
+		siteWords: siteWords,
+	}
+}
+
+// Screen lets a rejecting rule win over holding ones; masks apply as the
+// rules fire.
+func (fu *FilterUsecase) Screen(ctx context.Context, content *filters.Content) error {
+	if filters.IsApproved(ctx) {
+		return nil
+	}
+	settings, err := fu.repo.SelectSettings(ctx, content.Forum)
+	if err != nil {
+		return err
+	}
+	pipeline := newPipeline(settings, fu.siteWords)
+	if len(pipeline) == 0 {
+		return nil
+	}
+
+	var held *models.HeldContent
+	for i, text := range content.Texts {
+		for _, filter := range pipeline {
+			masked, detail, fired := filter.Apply(*text)
+			if !fired {
+				continue
+			}
+			switch filter.Action() {
+			case models.FilterReject:
+				return myerr.ContentRejected.WithMessage("rule %s: %s", filter.Rule(), detail)
+			case models.FilterMask:
+				*text = masked
+			case models.FilterHold:
+				if held == nil {
+					held = &models.HeldContent{
+						Kind:   content.Kind,
+						Forum:  content.Forum,
+						Thread: content.Thread,
+						Author: content.Authors[i],
+						Rule:   filter.Rule(),
+					}
+				}
+			}
+		}
+	}
+	if held == nil {
+		return nil
+	}
+
+	held.Payload, err = json.Marshal(content.Payload)
+	if err != nil {
+		return err
+	}
+	err = fu.repo.InsertHeld(ctx, held)
+	if err != nil {
+		return err
+	}
+	return myerr.ContentHeld.WithMessage("content is held for review by rule %s as #%d", held.Rule, held.Id)
+}
+
+func (fu *FilterUsecase) GetSettings(ctx context.Context, forum string) (*models.FilterSettings, error) {
+	err := auth.AuthorizeModerator(ctx, fu.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return fu.repo.SelectSettings(ctx, forum)
+}
+
+func (fu *FilterUsecase) UpdateSettings(ctx context.Context, settings *models.FilterSettings) (*models.FilterSettings, error) {
+	err := auth.AuthorizeModerator(ctx, fu.roles, settings.Forum)
+	if err != nil {
+		return nil, err
+	}
+	if !validSettings(settings) {
+		return nil, myerr.InvalidFilter
+	}
+	if settings.BannedWords == nil {
+		settings.BannedWords = make([]string, 0)
+	}
+	if settings.AllowedWords == nil {
+		settings.AllowedWords = make([]string, 0)
+	}
+
+	err = fu.repo.UpdateSettings(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func validSettings(settings *models.FilterSettings) bool {
+	for _, action := range []string{settings.BannedWordsAction, settings.MaxLengthAction, settings.MaxLinksAction, settings.ShoutingAction} {
+		if !models.ValidFilterAction(action) {
+			return false
+		}
+	}
+	return settings.MaxLength >= 0 && settings.MaxLinks >= 0
+}
+
+func (fu *FilterUsecase) GetHeld(ctx context.Context, forum string) ([]*models.HeldContent, error) {
+	err := auth.AuthorizeModerator(ctx, fu.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return fu.repo.SelectHeld(ctx, forum)
+}
+
+func (fu *FilterUsecase) GetHeldItem(ctx context.Context, forum string, id int64) (*models.HeldContent, error) {
+	err := auth.AuthorizeModerator(ctx, fu.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return fu.repo.SelectHeldItem(ctx, forum, id)
+}
+
+func (fu *FilterUsecase) DropHeld(ctx context.Context, forum string, id int64) error {
+	err := auth.AuthorizeModerator(ctx, fu.roles, forum)
+	if err != nil {
+		return err
+	}
+	return fu.repo.DeleteHeld(ctx, forum, id)
+}
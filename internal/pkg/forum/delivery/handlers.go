@@ -2,10 +2,12 @@ package delivery
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/cursor"
 	"forum/internal/pkg/forum"
+	"forum/internal/pkg/response"
 	"io/ioutil"
 	"net/http"
 
@@ -14,11 +16,13 @@ import (
 
 type ForumDelivery struct {
 	forumUsecase forum.ForumUsecase
+	cursors      *cursor.Codec
 }
 
-func NewForumDelivery(forumUsecase forum.ForumUsecase) *ForumDelivery {
+func NewForumDelivery(forumUsecase forum.ForumUsecase, cursors *cursor.Codec) *ForumDelivery {
 	return &ForumDelivery{
 		forumUsecase: forumUsecase,
+		cursors:      cursors,
 	}
 }
 
@@ -33,65 +37,68 @@ func (fd *ForumDelivery) CreateForumHandler(w http.ResponseWriter, r *http.Reque
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, forumInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	forum := forumInput.ToDefaultForum()
-	forum, err = fd.forumUsecase.CreateForum(forum)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusCreated)
-		w.Write(models.ToBytes(forum))
-	case myerr.UserNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("Can't find forum's owner: %s", forum.User)}))
-	case myerr.ForumAlreadyExist:
-		w.WriteHeader(http.StatusConflict)
-		w.Write(models.ToBytes(forum))
+	forum, err = fd.forumUsecase.CreateForum(r.Context(), forum)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, forum)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find forum's owner: %s", forumInput.User))
+	case errors.Is(err, myerr.ForumAlreadyExist):
+		response.JSON(w, http.StatusConflict, forum)
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
 func (fd *ForumDelivery) GetForumHandler(w http.ResponseWriter, r *http.Request) {
 	slug := mux.Vars(r)["slug"]
 
-	forum, err := fd.forumUsecase.GetForum(slug)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(forum))
-	case myerr.NoRows:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("forum %s not found", slug)}))
+	forum, err := fd.forumUsecase.GetForum(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, forum)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("forum %s not found", slug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
 func (fd *ForumDelivery) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 	fv := models.NewForumUsersQuery(mux.Vars(r), r.URL.Query())
-	users, err := fd.forumUsecase.GetUsersByForum(fv)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(users))
-	case myerr.ForumNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("forum %s not found", fv.ForumSlug)}))
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		fv.After = &models.ForumUsersCursor{}
+		err := fd.cursors.Decode(token, fv.After)
+		if err != nil || fv.After.Desc != (fv.Sorting == "DESC") {
+			response.Error(w, r, myerr.InvalidCursor)
+			return
+		}
+	}
+
+	users, err := fd.forumUsecase.GetUsersByForum(r.Context(), fv)
+	switch {
+	case err == nil:
+		if len(users) != 0 && int64(len(users)) == fv.Limit {
+			response.NextLink(w, r, fd.cursors.Encode(&models.ForumUsersCursor{
+				Nickname: users[len(users)-1].Nickname,
+				Desc:     fv.Sorting == "DESC",
+			}))
+		}
+		response.JSON(w, http.StatusOK, users)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("forum %s not found", fv.ForumSlug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
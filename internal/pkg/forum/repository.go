@@ -1,9 +1,12 @@
 package forum
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type ForumRepository interface {
-	InsertForum(forum *models.Forum) error
-	SelectForum(slug string) (*models.Forum, error)
-	SelectUsers(fv *models.ForumUsersQuery) ([]*models.User, error)
+	InsertForum(ctx context.Context, forum *models.Forum) error
+	SelectForum(ctx context.Context, slug string) (*models.Forum, error)
+	SelectUsers(ctx context.Context, fv *models.ForumUsersQuery) ([]*models.User, error)
 }
@@ -7,30 +7,33 @@ import (
 	myerr "forum/internal/error"
 	"forum/internal/models"
 	"forum/internal/pkg/forum"
-	"log"
-	"regexp"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"time"
 )
 
 type ForumRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewForumRepository(db *sql.DB) forum.ForumRepository {
+func NewForumRepository(db *sql.DB, lg *logger.Logger) forum.ForumRepository {
 	return &ForumRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (fr *ForumRepository) InsertForum(forum *models.Forum) error {
-	tx, err := fr.db.BeginTx(context.Background(), nil)
+func (fr *ForumRepository) InsertForum(ctx context.Context, forum *models.Forum) error {
+	defer metrics.ObserveQuery("forum", "InsertForum", time.Now())
+
+	tx, err := fr.db.BeginTx(ctx, nil)
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
 	row := tx.QueryRowContext(
-		context.Background(),
+		ctx,
 		`INSERT INTO forum (slug, title, author) VALUES ($1, $2, COALESCE((SELECT nickname FROM users WHERE nickname = $3), $3)) RETURNING slug, title, author, posts, threads;`,
 		forum.Slug, forum.Title, forum.User,
 	)
@@ -42,17 +45,11 @@ func (fr *ForumRepository) InsertForum(forum *models.Forum) error {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*forum_pkey.*", []byte(err.Error()))
-		if res {
-			return myerr.ForumAlreadyExist
-		}
-		res, _ = regexp.Match(".*forum_author_fkey.*", []byte(err.Error()))
-		if res {
-			return myerr.UserNotExist
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
 		}
-
-		fr.logger.Printf(err.Error())
-		return myerr.InternalDbError
+		return dbErr
 	}
 
 	err = tx.Commit()
@@ -63,8 +60,11 @@ func (fr *ForumRepository) InsertForum(forum *models.Forum) error {
 	return nil
 }
 
-func (fr *ForumRepository) SelectForum(slug string) (*models.Forum, error) {
-	row := fr.db.QueryRow(
+func (fr *ForumRepository) SelectForum(ctx context.Context, slug string) (*models.Forum, error) {
+	defer metrics.ObserveQuery("forum", "SelectForum", time.Now())
+
+	row := fr.db.QueryRowContext(
+		ctx,
 		`SELECT slug, title, author, posts, threads FROM forum WHERE slug = $1`,
 		slug,
 	)
@@ -72,21 +72,19 @@ func (fr *ForumRepository) SelectForum(slug string) (*models.Forum, error) {
 	forum := &models.Forum{}
 	err := row.Scan(&forum.Slug, &forum.Title, &forum.User, &forum.Posts, &forum.Threads)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.NoRows
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			fr.logger.Error(ctx, err.Error())
 		}
-	}
-
-	if err != nil {
-		fr.logger.Printf(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 
 	return forum, nil
 }
 
-func (fr *ForumRepository) SelectUsers(fv *models.ForumUsersQuery) ([]*models.User, error) {
+func (fr *ForumRepository) SelectUsers(ctx context.Context, fv *models.ForumUsersQuery) ([]*models.User, error) {
+	defer metrics.ObserveQuery("forum", "SelectUsers", time.Now())
+
 	queryStr := `
 					SELECT nickname, fullname, about, email
 					FROM forum_users
@@ -96,15 +94,19 @@ func (fr *ForumRepository) SelectUsers(fv *models.ForumUsersQuery) ([]*models.Us
 				`
 	var rows *sql.Rows
 	var err error
-	if fv.Since != "" {
+	since := fv.Since
+	if fv.After != nil {
+		since = fv.After.Nickname
+	}
+	if since != "" {
 		queryStr = fmt.Sprintf(queryStr, fmt.Sprintf(`AND nickname %s $3`, fv.Sign), fv.Sorting)
-		rows, err = fr.db.Query(queryStr, fv.ForumSlug, fv.Limit, fv.Since)
+		rows, err = fr.db.QueryContext(ctx, queryStr, fv.ForumSlug, fv.Limit, since)
 	} else {
 		queryStr = fmt.Sprintf(queryStr, "", fv.Sorting)
-		rows, err = fr.db.Query(queryStr, fv.ForumSlug, fv.Limit)
+		rows, err = fr.db.QueryContext(ctx, queryStr, fv.ForumSlug, fv.Limit)
 	}
 	if err != nil {
-		fr.logger.Println(err.Error())
+		fr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
@@ -113,7 +115,7 @@ func (fr *ForumRepository) SelectUsers(fv *models.ForumUsersQuery) ([]*models.Us
 		user := &models.User{}
 		err = rows.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 		if err != nil {
-			fr.logger.Println(err.Error())
+			fr.logger.Error(ctx, err.Error())
 			return nil, myerr.InternalDbError
 		}
 
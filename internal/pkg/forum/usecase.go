@@ -1,9 +1,12 @@
 package forum
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type ForumUsecase interface {
-	CreateForum(forum *models.Forum) (*models.Forum, error)
-	GetForum(slug string) (*models.Forum, error)
-	GetUsersByForum(fv *models.ForumUsersQuery) ([]*models.User, error)
+	CreateForum(ctx context.Context, forum *models.Forum) (*models.Forum, error)
+	GetForum(ctx context.Context, slug string) (*models.Forum, error)
+	GetUsersByForum(ctx context.Context, fv *models.ForumUsersQuery) ([]*models.User, error)
 }
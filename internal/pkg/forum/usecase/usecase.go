@@ -1,8 +1,10 @@
 package usecase
 
 import (
+	"context"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
 	"forum/internal/pkg/forum"
 )
 
@@ -16,10 +18,20 @@ func NewForumUsecase(repo forum.ForumRepository) forum.ForumUsecase {
 	}
 }
 
-func (fu *ForumUsecase) CreateForum(forum *models.Forum) (*models.Forum, error) {
-	err := fu.repo.InsertForum(forum)
+// CreateForum makes forum.User, by default the authenticated user, the
+// owner of the new forum.
+func (fu *ForumUsecase) CreateForum(ctx context.Context, forum *models.Forum) (*models.Forum, error) {
+	if forum.User == "" {
+		forum.User = auth.Nickname(ctx)
+	}
+	err := auth.Authorize(ctx, forum.User)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fu.repo.InsertForum(ctx, forum)
 	if err == myerr.ForumAlreadyExist {
-		forum, err = fu.repo.SelectForum(forum.Slug)
+		forum, err = fu.repo.SelectForum(ctx, forum.Slug)
 		if err == nil {
 			err = myerr.ForumAlreadyExist
 		}
@@ -27,21 +39,17 @@ func (fu *ForumUsecase) CreateForum(forum *models.Forum) (*models.Forum, error)
 	return forum, err
 }
 
-func (fu *ForumUsecase) GetForum(slug string) (*models.Forum, error) {
-	forum, err := fu.repo.SelectForum(slug)
+func (fu *ForumUsecase) GetForum(ctx context.Context, slug string) (*models.Forum, error) {
+	forum, err := fu.repo.SelectForum(ctx, slug)
 	return forum, err
 }
 
-func (fu *ForumUsecase) GetUsersByForum(fv *models.ForumUsersQuery) ([]*models.User, error) {
-	_, err := fu.repo.SelectForum(fv.ForumSlug)
-	switch err {
-	case nil:
-	case myerr.NoRows:
-		return nil, myerr.ForumNotExist
-	default:
+func (fu *ForumUsecase) GetUsersByForum(ctx context.Context, fv *models.ForumUsersQuery) ([]*models.User, error) {
+	_, err := fu.repo.SelectForum(ctx, fv.ForumSlug)
+	if err != nil {
 		return nil, err
 	}
 
-	users, err := fu.repo.SelectUsers(fv)
+	users, err := fu.repo.SelectUsers(ctx, fv)
 	return users, err
 }
@@ -0,0 +1,42 @@
+package delivery
+
+import (
+	"forum/internal/models"
+	"forum/internal/pkg/health"
+	"forum/internal/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type HealthDelivery struct {
+	healthUsecase health.HealthUsecase
+}
+
+func NewHealthDelivery(healthUsecase health.HealthUsecase) *HealthDelivery {
+	return &HealthDelivery{
+		healthUsecase: healthUsecase,
+	}
+}
+
+// Routing mounts the probes outside /api, as they answer orchestration
+// rather than clients.
+func (hd *HealthDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/healthz", hd.LiveHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/readyz", hd.ReadyHandler).Methods(http.MethodGet, http.MethodHead)
+}
+
+func (hd *HealthDelivery) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	response.JSON(w, http.StatusOK, hd.healthUsecase.Live(r.Context()))
+}
+
+func (hd *HealthDelivery) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	status := hd.healthUsecase.Ready(r.Context())
+	if status.Status != models.HealthOk {
+		response.JSON(w, http.StatusServiceUnavailable, status)
+		return
+	}
+	response.JSON(w, http.StatusOK, status)
+}
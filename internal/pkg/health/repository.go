@@ -0,0 +1,18 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	PoolStats() sql.DBStats
+}
+
+// SchemaVersions tells which migration the database is at and which one
+// the binary expects, as db.Migrator does.
+type SchemaVersions interface {
+	CurrentVersion(ctx context.Context) (int64, error)
+	LatestVersion() int64
+}
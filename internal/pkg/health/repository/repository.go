@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum/internal/pkg/health"
+)
+
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepository(db *sql.DB) health.HealthRepository {
+	return &HealthRepository{
+		db: db,
+	}
+}
+
+func (hr *HealthRepository) Ping(ctx context.Context) error {
+	return hr.db.PingContext(ctx)
+}
+
+func (hr *HealthRepository) PoolStats() sql.DBStats {
+	return hr.db.Stats()
+}
@@ -0,0 +1,11 @@
+package health
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type HealthUsecase interface {
+	Live(ctx context.Context) *models.Health
+	Ready(ctx context.Context) *models.Health
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/pkg/health"
+	"forum/internal/pkg/logger"
+	"sync"
+	"time"
+)
+
+type HealthUsecase struct {
+	repo     health.HealthRepository
+	versions health.SchemaVersions
+	timeout  time.Duration
+	logger   *logger.Logger
+
+	// mu guards the pool wait totals seen by the previous probe.
+	mu           sync.Mutex
+	waitCount    int64
+	waitDuration time.Duration
+}
+
+// NewHealthUsecase bounds the database checks of a readiness probe by
+// timeout.
+func NewHealthUsecase(repo health.HealthRepository, versions health.SchemaVersions, timeout time.Duration, lg *logger.Logger) health.HealthUsecase {
+	return &HealthUsecase{
+		repo:     repo,
+		versions: versions,
+		timeout:  timeout,
+		logger:   lg,
+	}
+}
+
+// Live only tells the process is up to answer.
+func (hu *HealthUsecase) Live(ctx context.Context) *models.Health {
+	return &models.Health{Status: models.HealthOk}
+}
+
+// Ready checks the database answers, its schema is migrated and requests
+// do not queue for a connection. Details name what failed but never carry
+// the driver's error, which is logged instead.
+func (hu *HealthUsecase) Ready(ctx context.Context) *models.Health {
+	ctx, cancel := context.WithTimeout(ctx, hu.timeout)
+	defer cancel()
+
+	checks := []*models.HealthCheck{
+		hu.checkPool(ctx),
+		hu.checkDatabase(ctx),
+		hu.checkMigrations(ctx),
+	}
+	status := models.HealthOk
+	for _, check := range checks {
+		if check.Status != models.HealthOk {
+			status = models.HealthUnavailable
+		}
+	}
+	return &models.Health{Status: status, Checks: checks}
+}
+
+func (hu *HealthUsecase) checkDatabase(ctx context.Context) *models.HealthCheck {
+	check := &models.HealthCheck{Name: "database", Status: models.HealthOk}
+	start := time.Now()
+	err := hu.repo.Ping(ctx)
+	if err != nil {
+		hu.logger.Error(ctx, "readiness ping failed", "error", err)
+		check.Status = models.HealthUnavailable
+		check.Detail = "database did not answer"
+		if errors.Is(err, context.DeadlineExceeded) {
+			check.Detail = fmt.Sprintf("database did not answer within %s", hu.timeout)
+		}
+		return check
+	}
+	check.Detail = fmt.Sprintf("ping took %s", time.Since(start).Round(time.Microsecond))
+	return check
+}
+
+// checkMigrations lets a schema newer than the binary pass, so instances
+// of the previous release keep serving while a new one rolls out.
+func (hu *HealthUsecase) checkMigrations(ctx context.Context) *models.HealthCheck {
+	check := &models.HealthCheck{Name: "migrations", Status: models.HealthOk}
+	current, err := hu.versions.CurrentVersion(ctx)
+	if err != nil {
+		hu.logger.Error(ctx, "reading the schema version failed", "error", err)
+		check.Status = models.HealthUnavailable
+		check.Detail = "could not read the schema version"
+		return check
+	}
+
+	latest := hu.versions.LatestVersion()
+	check.Detail = fmt.Sprintf("at version %d, expecting %d", current, latest)
+	if current < latest {
+		check.Status = models.HealthUnavailable
+	}
+	return check
+}
+
+// checkPool fails when the requests that queued for a connection since the
+// previous probe waited longer than a probe may take on average. A pool
+// with every connection in use that hands them out quickly is only busy.
+func (hu *HealthUsecase) checkPool(ctx context.Context) *models.HealthCheck {
+	check := &models.HealthCheck{Name: "pool", Status: models.HealthOk}
+	stats := hu.repo.PoolStats()
+
+	hu.mu.Lock()
+	waits := stats.WaitCount - hu.waitCount
+	waited := stats.WaitDuration - hu.waitDuration
+	hu.waitCount, hu.waitDuration = stats.WaitCount, stats.WaitDuration
+	hu.mu.Unlock()
+
+	limit := "unlimited"
+	if stats.MaxOpenConnections > 0 {
+		limit = fmt.Sprint(stats.MaxOpenConnections)
+	}
+	check.Detail = fmt.Sprintf("%d of %s connections in use, %d waits since the last probe", stats.InUse, limit, waits)
+	if waits <= 0 {
+		return check
+	}
+
+	average := waited / time.Duration(waits)
+	check.Detail += fmt.Sprintf(" averaging %s", average.Round(time.Microsecond))
+	if average > hu.timeout {
+		hu.logger.Warn(ctx, "requests queue for database connections", "waits", waits, "average_wait", average)
+		check.Status = models.HealthUnavailable
+	}
+	return check
+}
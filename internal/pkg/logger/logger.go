@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels in increasing severity.
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// ParseLevel maps a config log level onto a level, info when unknown.
+func ParseLevel(name string) int {
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level
+		}
+	}
+	return LevelInfo
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the request ctx belongs to, "" outside one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type loggerKey struct{}
+
+// fallback serves code that logs outside a request the access log saw.
+var fallback = New(os.Stderr, "info")
+
+func WithLogger(ctx context.Context, lg *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, lg)
+}
+
+// FromContext returns the logger of the request ctx belongs to, one writing
+// to stderr outside a request.
+func FromContext(ctx context.Context) *Logger {
+	if lg, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return lg
+	}
+	return fallback
+}
+
+// Logger writes one JSON object per line. Every line logged on behalf of a
+// request carries its request_id.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level int
+}
+
+func New(out io.Writer, level string) *Logger {
+	return &Logger{
+		out:   out,
+		level: ParseLevel(level),
+	}
+}
+
+func (l *Logger) Enabled(level int) bool {
+	return level >= l.level
+}
+
+// Debug, Info, Warn and Error log msg with keyvals given as alternating
+// keys and values.
+func (l *Logger) Debug(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.log(ctx, LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(ctx context.Context, level int, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	writeField(buf, "time", time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteByte(',')
+	writeField(buf, "level", levelNames[level])
+	buf.WriteByte(',')
+	writeField(buf, "msg", msg)
+	if id := RequestID(ctx); id != "" {
+		buf.WriteByte(',')
+		writeField(buf, "request_id", id)
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		buf.WriteByte(',')
+		writeField(buf, fmt.Sprint(keyvals[i]), value)
+	}
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	}
+
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(encodedKey)
+	buf.WriteByte(':')
+	buf.Write(encodedValue)
+}
+
+// Writer turns every line written to it into an entry of the given level,
+// so that output of the standard log package ends up as JSON as well.
+func (l *Logger) Writer(level int) io.Writer {
+	return &lineWriter{logger: l, level: level}
+}
+
+type lineWriter struct {
+	logger *Logger
+	level  int
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		lw.logger.log(context.Background(), lw.level, line, nil)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	httpBuckets  = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	queryBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+)
+
+var (
+	HTTPRequests = NewCounterVec("forum_http_requests_total",
+		"HTTP requests served, by route template, method and status.",
+		"route", "method", "status")
+	HTTPDuration = NewHistogramVec("forum_http_request_duration_seconds",
+		"Time to serve HTTP requests, by route template, method and status.",
+		httpBuckets, "route", "method", "status")
+	QueryDuration = NewHistogramVec("forum_query_duration_seconds",
+		"Time spent in repository methods, by repository and method.",
+		queryBuckets, "repository", "method")
+)
+
+// Default holds the metrics of the service.
+var Default = NewRegistry()
+
+func init() {
+	Default.Register(HTTPRequests, HTTPDuration, QueryDuration)
+}
+
+// ObserveQuery records the time a repository method took since start. It
+// is meant to be deferred as the method's first statement.
+func ObserveQuery(repository string, method string, start time.Time) {
+	QueryDuration.Observe(time.Since(start).Seconds(), repository, method)
+}
+
+// DBStats exposes the connection pool statistics of db.
+func DBStats(db *sql.DB) Collector {
+	return CollectorFunc(func(w io.Writer) {
+		stats := db.Stats()
+		gauge := func(name string, help string, value float64) {
+			writeHeader(w, name, help, "gauge")
+			fmt.Fprintf(w, "%s %s\n", name, formatValue(value))
+		}
+		counter := func(name string, help string, value float64) {
+			writeHeader(w, name, help, "counter")
+			fmt.Fprintf(w, "%s %s\n", name, formatValue(value))
+		}
+
+		gauge("forum_db_max_open_connections", "Maximum number of open connections to the database.", float64(stats.MaxOpenConnections))
+		gauge("forum_db_open_connections", "Established connections, in use or idle.", float64(stats.OpenConnections))
+		gauge("forum_db_in_use_connections", "Connections currently in use.", float64(stats.InUse))
+		gauge("forum_db_idle_connections", "Idle connections.", float64(stats.Idle))
+		counter("forum_db_wait_count_total", "Connections waited for.", float64(stats.WaitCount))
+		counter("forum_db_wait_duration_seconds_total", "Time blocked waiting for a connection.", stats.WaitDuration.Seconds())
+		counter("forum_db_max_idle_closed_total", "Connections closed due to the idle limit.", float64(stats.MaxIdleClosed))
+		counter("forum_db_max_idle_time_closed_total", "Connections closed due to the idle time limit.", float64(stats.MaxIdleTimeClosed))
+		counter("forum_db_max_lifetime_closed_total", "Connections closed due to the lifetime limit.", float64(stats.MaxLifetimeClosed))
+	})
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector writes its samples in the Prometheus text exposition format.
+type Collector interface {
+	Collect(w io.Writer)
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Gather writes every registered collector in registration order.
+func (r *Registry) Gather(w io.Writer) {
+	r.mu.Lock()
+	collectors := make([]Collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	for _, collector := range collectors {
+		collector.Collect(w)
+	}
+}
+
+// Handler serves the registry to Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		r.Gather(buf)
+		buf.Flush()
+	})
+}
+
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders {name="value",...}, extra pairs going last.
+func formatLabels(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+labelEscaper.Replace(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func checkLabels(names []string, values []string) {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("metrics: %d label values for labels %v", len(values), names))
+	}
+}
+
+// CounterVec is a family of counters told apart by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counter
+}
+
+type counter struct {
+	values []string
+	value  float64
+}
+
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		series: make(map[string]*counter),
+	}
+}
+
+func (cv *CounterVec) Add(delta float64, values ...string) {
+	checkLabels(cv.labels, values)
+	key := seriesKey(values)
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	c, ok := cv.series[key]
+	if !ok {
+		c = &counter{values: append([]string(nil), values...)}
+		cv.series[key] = c
+	}
+	c.value += delta
+}
+
+func (cv *CounterVec) Inc(values ...string) {
+	cv.Add(1, values...)
+}
+
+func (cv *CounterVec) Collect(w io.Writer) {
+	writeHeader(w, cv.name, cv.help, "counter")
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	keys := make([]string, 0, len(cv.series))
+	for key := range cv.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c := cv.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", cv.name, formatLabels(cv.labels, c.values), formatValue(c.value))
+	}
+}
+
+// HistogramVec is a family of histograms with the same buckets told apart
+// by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec takes the upper bounds of the buckets in increasing
+// order; the +Inf bucket is implied.
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+}
+
+func (hv *HistogramVec) Observe(v float64, values ...string) {
+	checkLabels(hv.labels, values)
+	key := seriesKey(values)
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	h, ok := hv.series[key]
+	if !ok {
+		h = &histogram{
+			values: append([]string(nil), values...),
+			counts: make([]uint64, len(hv.buckets)),
+		}
+		hv.series[key] = h
+	}
+	i := sort.SearchFloat64s(hv.buckets, v)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (hv *HistogramVec) Collect(w io.Writer) {
+	writeHeader(w, hv.name, hv.help, "histogram")
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	keys := make([]string, 0, len(hv.series))
+	for key := range hv.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h := hv.series[key]
+		// the format wants cumulative buckets
+		var cumulative uint64
+		for i, bound := range hv.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, formatLabels(hv.labels, h.values, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, formatLabels(hv.labels, h.values, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", hv.name, formatLabels(hv.labels, h.values), formatValue(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", hv.name, formatLabels(hv.labels, h.values), h.count)
+	}
+}
+
+// CollectorFunc lets a plain function act as a Collector.
+type CollectorFunc func(w io.Writer)
+
+func (cf CollectorFunc) Collect(w io.Writer) {
+	cf(w)
+}
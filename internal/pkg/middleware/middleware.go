@@ -1,6 +1,18 @@
 package middleware
 
-import "net/http"
+import (
+	"context"
+	"crypto/subtle"
+	myerr "forum/internal/error"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/response"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
 
 func ContentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,3 +20,105 @@ func ContentTypeMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// StreamRoutePrefix starts the names of routes that keep the connection
+// open to push events. Such routes bound their own queries.
+const StreamRoutePrefix = "stream:"
+
+func isStream(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	return route != nil && strings.HasPrefix(route.GetName(), StreamRoutePrefix)
+}
+
+type connKey struct{}
+
+// ConnContext is meant for http.Server.ConnContext and keeps the connection
+// within reach of StreamDeadlineMiddleware.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// StreamDeadlineMiddleware lifts the server's write timeout off stream
+// routes, which it would otherwise cut, while every other route keeps it.
+// The server sets the deadline anew for the next request on the connection.
+func StreamDeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok && isStream(r) {
+			conn.SetWriteDeadline(time.Time{})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// QueryDeadlineMiddleware bounds the request context, and with it every
+// query started on behalf of the request, by timeout. Zero disables it.
+// Stream routes are left alone.
+func QueryDeadlineMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AdminTokenMiddleware grants admin rights to requests presenting the
+// configured X-Admin-Token. An empty token disables admin access.
+func AdminTokenMiddleware(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get("X-Admin-Token")
+			if presented != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Admin: true}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BearerTokenMiddleware authenticates requests carrying an Authorization:
+// Bearer token as the user it was issued to and refuses unknown tokens.
+// With enforce off the usecases let any request act as any user, as the
+// benchmark expects.
+func BearerTokenMiddleware(authenticator auth.Authenticator, enforce bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if !enforce {
+				ctx = auth.WithoutEnforcement(ctx)
+			}
+
+			header := r.Header.Get("Authorization")
+			if header != "" {
+				const scheme = "bearer "
+				if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) {
+					response.Error(w, r, myerr.Unauthorized)
+					return
+				}
+				nickname, err := authenticator.Authenticate(ctx, strings.TrimSpace(header[len(scheme):]))
+				if err != nil {
+					response.Error(w, r, err)
+					return
+				}
+
+				principal := &auth.Principal{Nickname: nickname}
+				if current := auth.FromContext(ctx); current != nil {
+					principal.Admin = current.Admin
+				}
+				ctx = auth.WithPrincipal(ctx, principal)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package delivery
+
+import (
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/pkg/moderators"
+	"forum/internal/pkg/response"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ModeratorDelivery struct {
+	moderatorUsecase moderators.ModeratorUsecase
+}
+
+func NewModeratorDelivery(moderatorUsecase moderators.ModeratorUsecase) *ModeratorDelivery {
+	return &ModeratorDelivery{
+		moderatorUsecase: moderatorUsecase,
+	}
+}
+
+func (md *ModeratorDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/forum/{slug}/moderators", md.GetModeratorsHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/moderators/{nickname}", md.AddModeratorHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/moderators/{nickname}", md.RemoveModeratorHandler).Methods(http.MethodDelete, http.MethodOptions)
+}
+
+func (md *ModeratorDelivery) GetModeratorsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	moderators, err := md.moderatorUsecase.GetModerators(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, moderators)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (md *ModeratorDelivery) AddModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	nickname := mux.Vars(r)["nickname"]
+	moderator, err := md.moderatorUsecase.AddModerator(r.Context(), slug, nickname)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, moderator)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (md *ModeratorDelivery) RemoveModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	nickname := mux.Vars(r)["nickname"]
+	err := md.moderatorUsecase.RemoveModerator(r.Context(), slug, nickname)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
@@ -0,0 +1,13 @@
+package moderators
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type ModeratorRepository interface {
+	SelectRole(ctx context.Context, forum string, nickname string) (string, error)
+	InsertModerator(ctx context.Context, forum string, nickname string, appointedBy string) (*models.Moderator, error)
+	DeleteModerator(ctx context.Context, forum string, nickname string) error
+	SelectModerators(ctx context.Context, forum string) ([]*models.Moderator, error)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/moderators"
+	"time"
+)
+
+type ModeratorRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewModeratorRepository(db *sql.DB, lg *logger.Logger) moderators.ModeratorRepository {
+	return &ModeratorRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+func (mr *ModeratorRepository) SelectRole(ctx context.Context, forum string, nickname string) (string, error) {
+	defer metrics.ObserveQuery("moderators", "SelectRole", time.Now())
+
+	var role string
+	row := mr.db.QueryRowContext(
+		ctx,
+		`SELECT CASE
+			WHEN f.author = $2 THEN $3
+			WHEN m.nickname IS NOT NULL THEN $4
+			ELSE '' END
+		 FROM forum f
+		 LEFT JOIN forum_moderators m ON m.forum = f.slug AND m.nickname = $2
+		 WHERE f.slug = $1;`,
+		forum, nickname, models.RoleOwner, models.RoleModerator)
+	err := row.Scan(&role)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			mr.logger.Error(ctx, err.Error())
+		}
+		return "", dbErr
+	}
+	return role, nil
+}
+
+// InsertModerator is idempotent: appointing a moderator twice keeps the
+// first appointment.
+func (mr *ModeratorRepository) InsertModerator(ctx context.Context, forum string, nickname string, appointedBy string) (*models.Moderator, error) {
+	defer metrics.ObserveQuery("moderators", "InsertModerator", time.Now())
+
+	moderator := &models.Moderator{Role: models.RoleModerator}
+	row := mr.db.QueryRowContext(
+		ctx,
+		`WITH inserted AS (
+			INSERT INTO forum_moderators (forum, nickname, appointedBy)
+			VALUES (
+				COALESCE((SELECT slug FROM forum WHERE slug = $1), $1),
+				COALESCE((SELECT nickname FROM users WHERE nickname = $2), $2),
+				$3
+			)
+			ON CONFLICT DO NOTHING
+			RETURNING forum, nickname, appointedBy, created
+		 )
+		 SELECT forum, nickname, appointedBy, created FROM inserted
+		 UNION ALL
+		 SELECT forum, nickname, appointedBy, created FROM forum_moderators
+		 WHERE forum = $1 AND nickname = $2;`,
+		forum, nickname, appointedBy)
+	err := row.Scan(&moderator.Forum, &moderator.Nickname, &moderator.AppointedBy, &moderator.Created)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			mr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	return moderator, nil
+}
+
+func (mr *ModeratorRepository) DeleteModerator(ctx context.Context, forum string, nickname string) error {
+	defer metrics.ObserveQuery("moderators", "DeleteModerator", time.Now())
+
+	res, err := mr.db.ExecContext(ctx, "DELETE FROM forum_moderators WHERE forum = $1 AND nickname = $2;", forum, nickname)
+	if err != nil {
+		mr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		mr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	if deleted == 0 {
+		return myerr.ModeratorNotExist
+	}
+	return nil
+}
+
+func (mr *ModeratorRepository) SelectModerators(ctx context.Context, forum string) ([]*models.Moderator, error) {
+	defer metrics.ObserveQuery("moderators", "SelectModerators", time.Now())
+
+	owner := &models.Moderator{Role: models.RoleOwner}
+	row := mr.db.QueryRowContext(ctx, "SELECT slug, author FROM forum WHERE slug = $1;", forum)
+	err := row.Scan(&owner.Forum, &owner.Nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			mr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	rows, err := mr.db.QueryContext(
+		ctx,
+		`SELECT forum, nickname, appointedBy, created FROM forum_moderators
+		 WHERE forum = $1 ORDER BY created, nickname;`,
+		owner.Forum)
+	if err != nil {
+		mr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	moderators := []*models.Moderator{owner}
+	for rows.Next() {
+		moderator := &models.Moderator{Role: models.RoleModerator}
+		err = rows.Scan(&moderator.Forum, &moderator.Nickname, &moderator.AppointedBy, &moderator.Created)
+		if err != nil {
+			mr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		moderators = append(moderators, moderator)
+	}
+	return moderators, nil
+}
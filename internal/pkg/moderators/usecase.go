@@ -0,0 +1,13 @@
+package moderators
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type ModeratorUsecase interface {
+	ForumRole(ctx context.Context, forum string, nickname string) (string, error)
+	AddModerator(ctx context.Context, forum string, nickname string) (*models.Moderator, error)
+	RemoveModerator(ctx context.Context, forum string, nickname string) error
+	GetModerators(ctx context.Context, forum string) ([]*models.Moderator, error)
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/moderators"
+)
+
+type ModeratorUsecase struct {
+	repo moderators.ModeratorRepository
+}
+
+func NewModeratorUsecase(repo moderators.ModeratorRepository) moderators.ModeratorUsecase {
+	return &ModeratorUsecase{
+		repo: repo,
+	}
+}
+
+func (mu *ModeratorUsecase) ForumRole(ctx context.Context, forum string, nickname string) (string, error) {
+	return mu.repo.SelectRole(ctx, forum, nickname)
+}
+
+// AddModerator and RemoveModerator are reserved to site admins and the
+// owner of the forum.
+func (mu *ModeratorUsecase) AddModerator(ctx context.Context, forum string, nickname string) (*models.Moderator, error) {
+	err := mu.authorizeOwner(ctx, forum)
+	if err != nil {
+		return nil, err
+	}
+	return mu.repo.InsertModerator(ctx, forum, nickname, auth.Nickname(ctx))
+}
+
+func (mu *ModeratorUsecase) RemoveModerator(ctx context.Context, forum string, nickname string) error {
+	err := mu.authorizeOwner(ctx, forum)
+	if err != nil {
+		return err
+	}
+	return mu.repo.DeleteModerator(ctx, forum, nickname)
+}
+
+func (mu *ModeratorUsecase) GetModerators(ctx context.Context, forum string) ([]*models.Moderator, error) {
+	return mu.repo.SelectModerators(ctx, forum)
+}
+
+func (mu *ModeratorUsecase) authorizeOwner(ctx context.Context, forum string) error {
+	if auth.IsAdmin(ctx) {
+		return nil
+	}
+	nickname := auth.Nickname(ctx)
+	if nickname == "" {
+		return myerr.Unauthorized
+	}
+
+	role, err := mu.repo.SelectRole(ctx, forum, nickname)
+	if err != nil {
+		return err
+	}
+	if role != models.RoleOwner {
+		return myerr.Forbidden
+	}
+	return nil
+}
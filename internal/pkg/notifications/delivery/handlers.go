@@ -0,0 +1,137 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/notifications"
+	"forum/internal/pkg/response"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationDelivery struct {
+	notificationUsecase notifications.NotificationUsecase
+}
+
+func NewNotificationDelivery(notificationUsecase notifications.NotificationUsecase) *NotificationDelivery {
+	return &NotificationDelivery{
+		notificationUsecase: notificationUsecase,
+	}
+}
+
+func (nd *NotificationDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/thread/{slug_or_id}/subscribe", nd.SubscribeHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/thread/{slug_or_id}/subscribe", nd.UnsubscribeHandler).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/notifications", nd.GetNotificationsHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/notifications/read", nd.MarkReadHandler).Methods(http.MethodPost, http.MethodOptions)
+}
+
+func threadKey(r *http.Request) (string, int64) {
+	slug := mux.Vars(r)["slug_or_id"]
+	id, err := strconv.ParseInt(slug, 10, 64)
+	if err == nil {
+		return "", id
+	}
+	return slug, 0
+}
+
+func (nd *NotificationDelivery) SubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	slug, id := threadKey(r)
+	subscription := &models.ThreadSubscription{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	if len(buf) != 0 {
+		err = json.Unmarshal(buf, subscription)
+		if err != nil {
+			response.Error(w, r, myerr.InvalidBody)
+			return
+		}
+	}
+
+	subscription, err = nd.notificationUsecase.Subscribe(r.Context(), slug, id, subscription.Nickname)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, subscription)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+// UnsubscribeHandler takes the nickname from the query, or from a body
+// shaped like the subscribe one, or from the bearer token.
+func (nd *NotificationDelivery) UnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	slug, id := threadKey(r)
+	subscription := &models.ThreadSubscription{Nickname: r.URL.Query().Get("nickname")}
+	if subscription.Nickname == "" {
+		defer r.Body.Close()
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(buf) != 0 && json.Unmarshal(buf, subscription) != nil {
+			response.Error(w, r, myerr.InvalidBody)
+			return
+		}
+	}
+
+	err := nd.notificationUsecase.Unsubscribe(r.Context(), slug, id, subscription.Nickname)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (nd *NotificationDelivery) GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	nq := models.NewNotificationsQuery(mux.Vars(r), r.URL.Query())
+	page, err := nd.notificationUsecase.GetNotifications(r.Context(), nq)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, page)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nq.Nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (nd *NotificationDelivery) MarkReadHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	read := &models.NotificationsRead{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	if len(buf) != 0 {
+		err = json.Unmarshal(buf, read)
+		if err != nil {
+			response.Error(w, r, myerr.InvalidBody)
+			return
+		}
+	}
+
+	unread, err := nd.notificationUsecase.MarkRead(r.Context(), nickname, read.Ids)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, map[string]int64{"unread": unread})
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
@@ -0,0 +1,14 @@
+package notifications
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type NotificationRepository interface {
+	InsertSubscription(ctx context.Context, slug string, id int64, nickname string) (*models.ThreadSubscription, error)
+	DeleteSubscription(ctx context.Context, slug string, id int64, nickname string) error
+	SelectNotifications(ctx context.Context, nq *models.NotificationsQuery) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, nickname string) (int64, error)
+	MarkRead(ctx context.Context, nickname string, ids []int64) (int64, error)
+}
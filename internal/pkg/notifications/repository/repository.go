@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/notifications"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type NotificationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewNotificationRepository(db *sql.DB, lg *logger.Logger) notifications.NotificationRepository {
+	return &NotificationRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+// InsertSubscription is idempotent: following a thread twice is not an
+// error.
+func (nr *NotificationRepository) InsertSubscription(ctx context.Context, slug string, id int64, nickname string) (*models.ThreadSubscription, error) {
+	defer metrics.ObserveQuery("notifications", "InsertSubscription", time.Now())
+
+	subscription := &models.ThreadSubscription{}
+	row := nr.db.QueryRowContext(
+		ctx,
+		`WITH thread AS (
+			SELECT id FROM threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1
+		 ), inserted AS (
+			INSERT INTO thread_subscriptions (thread, nickname)
+			SELECT thread.id, COALESCE((SELECT nickname FROM users WHERE nickname = $3), $3) FROM thread
+			ON CONFLICT DO NOTHING
+			RETURNING thread, nickname
+		 )
+		 SELECT thread, nickname FROM inserted
+		 UNION ALL
+		 SELECT s.thread, s.nickname FROM thread_subscriptions s, thread
+		 WHERE s.thread = thread.id AND s.nickname = $3;`,
+		id, slug, nickname)
+	err := row.Scan(&subscription.Thread, &subscription.Nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	return subscription, nil
+}
+
+func (nr *NotificationRepository) DeleteSubscription(ctx context.Context, slug string, id int64, nickname string) error {
+	defer metrics.ObserveQuery("notifications", "DeleteSubscription", time.Now())
+
+	var threadId int64
+	row := nr.db.QueryRowContext(ctx, "SELECT id from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1", id, slug)
+	err := row.Scan(&threadId)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+
+	_, err = nr.db.ExecContext(ctx, "DELETE FROM thread_subscriptions WHERE thread = $1 AND nickname = $2;", threadId, nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (nr *NotificationRepository) SelectNotifications(ctx context.Context, nq *models.NotificationsQuery) ([]*models.Notification, error) {
+	defer metrics.ObserveQuery("notifications", "SelectNotifications", time.Now())
+
+	row := nr.db.QueryRowContext(ctx, "SELECT nickname FROM users WHERE nickname = $1", nq.Nickname)
+	err := row.Scan(&nq.Nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT id, kind, post, thread, author, isRead, created
+		FROM notifications
+		WHERE nickname = $1 AND ($2 = 0 OR id %s $2) AND (NOT $3 OR NOT isRead)
+		ORDER BY id %s
+		LIMIT $4;`,
+		nq.Sign, nq.Sorting)
+	rows, err := nr.db.QueryContext(ctx, queryStr, nq.Nickname, nq.Since, nq.UnreadOnly, nq.Limit)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	notes := make([]*models.Notification, 0)
+	for rows.Next() {
+		note := &models.Notification{}
+		err = rows.Scan(&note.Id, &note.Kind, &note.Post, &note.Thread, &note.Author, &note.IsRead, &note.Created)
+		if err != nil {
+			nr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+func (nr *NotificationRepository) CountUnread(ctx context.Context, nickname string) (int64, error) {
+	defer metrics.ObserveQuery("notifications", "CountUnread", time.Now())
+
+	var unread int64
+	row := nr.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE nickname = $1 AND NOT isRead;", nickname)
+	err := row.Scan(&unread)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+	return unread, nil
+}
+
+// MarkRead marks the given notifications of the user as read, or all of
+// them when ids is empty, and returns how many stay unread.
+func (nr *NotificationRepository) MarkRead(ctx context.Context, nickname string, ids []int64) (int64, error) {
+	defer metrics.ObserveQuery("notifications", "MarkRead", time.Now())
+
+	row := nr.db.QueryRowContext(ctx, "SELECT nickname FROM users WHERE nickname = $1", nickname)
+	err := row.Scan(&nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+
+	_, err = nr.db.ExecContext(
+		ctx,
+		`UPDATE notifications SET isRead = TRUE
+		 WHERE nickname = $1 AND NOT isRead AND (cardinality($2::BIGINT[]) = 0 OR id = ANY($2::BIGINT[]));`,
+		nickname, pq.Array(ids))
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			nr.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+	return nr.CountUnread(ctx, nickname)
+}
@@ -0,0 +1,13 @@
+package notifications
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type NotificationUsecase interface {
+	Subscribe(ctx context.Context, slug string, id int64, nickname string) (*models.ThreadSubscription, error)
+	Unsubscribe(ctx context.Context, slug string, id int64, nickname string) error
+	GetNotifications(ctx context.Context, nq *models.NotificationsQuery) (*models.NotificationsPage, error)
+	MarkRead(ctx context.Context, nickname string, ids []int64) (int64, error)
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/notifications"
+)
+
+type NotificationUsecase struct {
+	repo notifications.NotificationRepository
+}
+
+func NewNotificationUsecase(repo notifications.NotificationRepository) notifications.NotificationUsecase {
+	return &NotificationUsecase{
+		repo: repo,
+	}
+}
+
+// Subscribe follows the thread as the authenticated user when no nickname
+// is given. So does Unsubscribe.
+func (nu *NotificationUsecase) Subscribe(ctx context.Context, slug string, id int64, nickname string) (*models.ThreadSubscription, error) {
+	if nickname == "" {
+		nickname = auth.Nickname(ctx)
+	}
+	if nickname == "" {
+		return nil, myerr.InvalidBody.WithMessage("nickname is required")
+	}
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	return nu.repo.InsertSubscription(ctx, slug, id, nickname)
+}
+
+func (nu *NotificationUsecase) Unsubscribe(ctx context.Context, slug string, id int64, nickname string) error {
+	if nickname == "" {
+		nickname = auth.Nickname(ctx)
+	}
+	if nickname == "" {
+		return myerr.InvalidBody.WithMessage("nickname is required")
+	}
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return err
+	}
+	return nu.repo.DeleteSubscription(ctx, slug, id, nickname)
+}
+
+func (nu *NotificationUsecase) GetNotifications(ctx context.Context, nq *models.NotificationsQuery) (*models.NotificationsPage, error) {
+	err := auth.Authorize(ctx, nq.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := nu.repo.SelectNotifications(ctx, nq)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := nu.repo.CountUnread(ctx, nq.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationsPage{
+		Unread:        unread,
+		Notifications: notes,
+	}, nil
+}
+
+func (nu *NotificationUsecase) MarkRead(ctx context.Context, nickname string, ids []int64) (int64, error) {
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return 0, err
+	}
+	return nu.repo.MarkRead(ctx, nickname, ids)
+}
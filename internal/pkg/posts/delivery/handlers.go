@@ -2,10 +2,12 @@ package delivery
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/cursor"
 	"forum/internal/pkg/posts"
+	"forum/internal/pkg/response"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -15,11 +17,13 @@ import (
 
 type PostDelivery struct {
 	postUsecase posts.PostUsecase
+	cursors     *cursor.Codec
 }
 
-func NewPostDelivery(postUsecase posts.PostUsecase) *PostDelivery {
+func NewPostDelivery(postUsecase posts.PostUsecase, cursors *cursor.Codec) *PostDelivery {
 	return &PostDelivery{
 		postUsecase: postUsecase,
+		cursors:     cursors,
 	}
 }
 
@@ -28,6 +32,11 @@ func (pd *PostDelivery) Routing(r *mux.Router) {
 	r.HandleFunc("/thread/{slug_or_id}/posts", pd.GetPostsByThreadHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/post/{id}/details", pd.GetPostDetailHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/post/{id}/details", pd.UpdatePostHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/post/{id}", pd.DeletePostHandler).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/post/{id}/history", pd.GetHistoryHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/post/{id}/history/diff", pd.GetDiffHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/post/{id}/history/{rev:[0-9]+}", pd.GetRevisionHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/mentions", pd.GetMentionsHandler).Methods(http.MethodGet, http.MethodOptions)
 }
 
 func (pd *PostDelivery) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -42,74 +51,64 @@ func (pd *PostDelivery) CreatePostHandler(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, &postsInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
-		return
-	}
-
-	posts, err := pd.postUsecase.CreatePostsBySlugOrId(slug, id, postsInput)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusCreated)
-		w.Write(models.ToBytes(posts))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("thread {slug: %s, id: %d} not found", slug, id)}))
-	case myerr.ParentNotExist:
-		w.WriteHeader(http.StatusConflict)
-		w.Write(models.ToBytes(models.Error{Message: "one parent not found"}))
-	case myerr.UserNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: "one user not found"}))
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	posts, err := pd.postUsecase.CreatePostsBySlugOrId(r.Context(), slug, id, postsInput)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, posts)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread {slug: %s, id: %d} not found", slug, id))
+	case errors.Is(err, myerr.ParentNotExist):
+		response.Error(w, r, myerr.ParentNotExist.WithMessage("one parent not found"))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("one user not found"))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
 func (pd *PostDelivery) GetPostsByThreadHandler(w http.ResponseWriter, r *http.Request) {
 	tq := models.NewThreadQuery(mux.Vars(r), r.URL.Query())
-	posts, err := pd.postUsecase.GetPostsRec(tq)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(posts))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("thread {slug: '%s', id: %d} not found", tq.ThreadSlug, tq.ThreadId)}))
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		tq.After = &models.PostsCursor{}
+		err := pd.cursors.Decode(token, tq.After)
+		if err != nil || tq.After.Sort != tq.Sort || tq.After.Desc != (tq.Sorting == "DESC") {
+			response.Error(w, r, myerr.InvalidCursor)
+			return
+		}
+	}
+
+	posts, err := pd.postUsecase.GetPostsRec(r.Context(), tq)
+	switch {
+	case err == nil:
+		if next := nextPostsCursor(tq, posts); next != nil {
+			response.NextLink(w, r, pd.cursors.Encode(next))
+		}
+		response.JSON(w, http.StatusOK, posts)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread {slug: '%s', id: %d} not found", tq.ThreadSlug, tq.ThreadId))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
 func (pd *PostDelivery) GetPostDetailHandler(w http.ResponseWriter, r *http.Request) {
 	pq := models.NewPostQuery(mux.Vars(r), r)
-	info, err := pd.postUsecase.GetInfo(pq)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(info))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: "thread not found"}))
-	case myerr.ForumNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: "forum not found"}))
-	case myerr.PostNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: "post not found"}))
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+	info, err := pd.postUsecase.GetInfo(r.Context(), pq)
+	if err != nil {
+		response.Error(w, r, err)
+		return
 	}
+	response.JSON(w, http.StatusOK, info)
 }
 
 func (pd *PostDelivery) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -117,15 +116,13 @@ func (pd *PostDelivery) UpdatePostHandler(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, &pu)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
@@ -133,16 +130,151 @@ func (pd *PostDelivery) UpdatePostHandler(w http.ResponseWriter, r *http.Request
 	if err == nil {
 		pu.Id = id
 	}
-	post, err := pd.postUsecase.UpdatePost(pu)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(post))
-	case myerr.PostNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: "post not found"}))
+	post, err := pd.postUsecase.UpdatePost(r.Context(), pu)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, post)
+}
+
+// DeletePostHandler replaces the post with a tombstone so its replies keep
+// their place in the tree. ?hard=true removes the post and every reply.
+func (pd *PostDelivery) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.Error(w, r, myerr.PostNotExist)
+		return
+	}
+
+	if hard, _ := strconv.ParseBool(r.URL.Query().Get("hard")); hard {
+		_, err = pd.postUsecase.DeletePostTree(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	post, err := pd.postUsecase.DeletePost(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, post)
+}
+
+func (pd *PostDelivery) GetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.Error(w, r, myerr.PostNotExist)
+		return
+	}
+
+	revisions, err := pd.postUsecase.GetHistory(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, revisions)
+}
+
+func (pd *PostDelivery) GetRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.Error(w, r, myerr.PostNotExist)
+		return
+	}
+	rev, err := strconv.ParseInt(mux.Vars(r)["rev"], 10, 64)
+	if err != nil {
+		response.Error(w, r, myerr.RevisionNotExist)
+		return
+	}
+
+	revision, err := pd.postUsecase.GetRevision(r.Context(), id, rev)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, revision)
+}
+
+// GetDiffHandler compares ?from= with ?to=; either may be omitted.
+func (pd *PostDelivery) GetDiffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.Error(w, r, myerr.PostNotExist)
+		return
+	}
+
+	var from, to int64
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		if from, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			response.Error(w, r, myerr.RevisionNotExist.WithMessage("revision %q not exist", raw))
+			return
+		}
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		if to, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			response.Error(w, r, myerr.RevisionNotExist.WithMessage("revision %q not exist", raw))
+			return
+		}
+	}
+
+	postDiff, err := pd.postUsecase.GetDiff(r.Context(), id, from, to)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, postDiff)
+}
+
+// nextPostsCursor returns the key the following page starts after, or nil
+// when the page is not full. parent_tree pages are limited by root posts.
+func nextPostsCursor(tq *models.ThreadsQuery, posts []*models.Post) *models.PostsCursor {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	next := &models.PostsCursor{Sort: tq.Sort, Desc: tq.Sorting == "DESC"}
+	last := posts[len(posts)-1]
+	switch tq.Sort {
+	case "parent_tree":
+		var roots int64
+		for _, post := range posts {
+			if post.Parent == 0 {
+				roots++
+				next.Id = post.Id
+			}
+		}
+		if roots != tq.Limit {
+			return nil
+		}
+	case "flat":
+		if int64(len(posts)) != tq.Limit {
+			return nil
+		}
+		next.Created = last.Created
+		next.Id = last.Id
+	default:
+		if int64(len(posts)) != tq.Limit {
+			return nil
+		}
+		next.Id = last.Id
+	}
+	return next
+}
+
+func (pd *PostDelivery) GetMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	mq := models.NewMentionsQuery(mux.Vars(r), r.URL.Query())
+	posts, err := pd.postUsecase.GetMentions(r.Context(), mq)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, posts)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", mq.Nickname))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
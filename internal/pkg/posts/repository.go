@@ -1,16 +1,23 @@
 package posts
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type PostRepository interface {
-	SelectFormSlugByThread(slug string, id int64) (string, int64, error)
-	CreatePost(inputPost *models.PostInput, dt string, forumSlug string, threadId int64) (*models.Post, error)
-	CreatePosts(inputPost []*models.PostInput, dt string, forumSlug string, threadId int64) ([]*models.Post, error)
-	SelectThreadsBySort(tq *models.ThreadsQuery) ([]*models.Post, error)
-	SelectThread(id int64, slug string) (int64, error)
-	SelectPost(id int64) (*models.Post, error)
-	SelectUser(nickname string) (*models.User, error)
-	SelectThreadById(id int64) (*models.Thread, error)
-	SelectForum(slug string) (*models.Forum, error)
-	UpdatePost(postupdate *models.PostUpdate) (*models.Post, error)
+	SelectFormSlugByThread(ctx context.Context, slug string, id int64) (string, int64, string, error)
+	CreatePost(ctx context.Context, inputPost *models.PostInput, dt string, forumSlug string, threadId int64) (*models.Post, error)
+	CreatePosts(ctx context.Context, inputPost []*models.PostInput, dt string, forumSlug string, threadId int64) ([]*models.Post, error)
+	SelectThreadsBySort(ctx context.Context, tq *models.ThreadsQuery) ([]*models.Post, error)
+	SelectThread(ctx context.Context, id int64, slug string) (int64, error)
+	SelectPost(ctx context.Context, id int64) (*models.Post, error)
+	SelectUser(ctx context.Context, nickname string) (*models.User, error)
+	SelectThreadById(ctx context.Context, id int64) (*models.Thread, error)
+	SelectForum(ctx context.Context, slug string) (*models.Forum, error)
+	UpdatePost(ctx context.Context, postupdate *models.PostUpdate) (*models.Post, error)
+	DeletePost(ctx context.Context, id int64, editor string) (*models.Post, error)
+	DeletePostTree(ctx context.Context, id int64) (int64, error)
+	SelectRevisions(ctx context.Context, id int64) ([]*models.PostRevision, error)
+	SelectMentions(ctx context.Context, mq *models.MentionsQuery) ([]*models.Post, error)
 }
@@ -6,81 +6,88 @@ import (
 	"fmt"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
 	"forum/internal/pkg/posts"
-	"log"
-	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type PostRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewPostRepository(db *sql.DB) posts.PostRepository {
+func NewPostRepository(db *sql.DB, lg *logger.Logger) posts.PostRepository {
 	return &PostRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (pr *PostRepository) SelectFormSlugByThread(slug string, id int64) (string, int64, error) {
-	queryStr := "SELECT forum, id FROM threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1"
-	row := pr.db.QueryRow(queryStr, id, slug)
-	var forumSlug string
+func (pr *PostRepository) SelectFormSlugByThread(ctx context.Context, slug string, id int64) (string, int64, string, error) {
+	defer metrics.ObserveQuery("posts", "SelectFormSlugByThread", time.Now())
+
+	queryStr := "SELECT forum, id, status FROM threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1"
+	row := pr.db.QueryRowContext(ctx, queryStr, id, slug)
+	var forumSlug, status string
 	var threadId int64
-	err := row.Scan(&forumSlug, &threadId)
+	err := row.Scan(&forumSlug, &threadId, &status)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return "", 0, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		pr.logger.Println(err.Error())
-		return "", 0, myerr.InternalDbError
+		return "", 0, "", dbErr
 	}
-	return forumSlug, threadId, nil
+	return forumSlug, threadId, status, nil
 }
 
-func (pr *PostRepository) CheckNickname(nickname string) (string, error) {
-	row := pr.db.QueryRow("SELECT nickname FROM users WHERE nickname = $1;", nickname)
+func (pr *PostRepository) CheckNickname(ctx context.Context, nickname string) (string, error) {
+	defer metrics.ObserveQuery("posts", "CheckNickname", time.Now())
+
+	row := pr.db.QueryRowContext(ctx, "SELECT nickname FROM users WHERE nickname = $1;", nickname)
 	err := row.Scan(&nickname)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return "", myerr.UserNotExist
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		pr.logger.Println(err.Error())
-		return "", myerr.InternalDbError
+		return "", dbErr
 	}
 	return nickname, nil
 }
 
-func (pr *PostRepository) CheckParent(threadId int64, parent int64) ([]int64, error) {
+func (pr *PostRepository) CheckParent(ctx context.Context, threadId int64, parent int64) ([]int64, error) {
+	defer metrics.ObserveQuery("posts", "CheckParent", time.Now())
+
 	path := make([]int64, 0)
-	row := pr.db.QueryRow("SELECT array_append(path, id) FROM posts WHERE id = $1 AND thread = $2;", parent, threadId)
+	row := pr.db.QueryRowContext(ctx, "SELECT array_append(path, id) FROM posts WHERE id = $1 AND thread = $2;", parent, threadId)
 	err := row.Scan(pq.Array(&path))
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ParentNotExist
+		dbErr, known := myerr.FromDb(err, myerr.ParentNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		pr.logger.Println(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return path, nil
 }
 
-func (pr *PostRepository) CreatePosts(inputPost []*models.PostInput, dt string, forumSlug string, threadId int64) ([]*models.Post, error) {
+func (pr *PostRepository) CreatePosts(ctx context.Context, inputPost []*models.PostInput, dt string, forumSlug string, threadId int64) ([]*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "CreatePosts", time.Now())
+
 	queryStr := "INSERT INTO posts (message, forum, thread, created, author, parent, path) VALUES"
 	args := make([]interface{}, 0)
 	var err1, err2 error = nil, nil
 	for ind, ip := range inputPost {
 		path := make([]int64, 0)
-		ip.Author, err1 = pr.CheckNickname(ip.Author)
+		ip.Author, err1 = pr.CheckNickname(ctx, ip.Author)
 		if ip.Parent != 0 {
-			path, err2 = pr.CheckParent(threadId, ip.Parent)
+			path, err2 = pr.CheckParent(ctx, threadId, ip.Parent)
 		} else {
 			err2 = nil
 		}
@@ -99,17 +106,18 @@ func (pr *PostRepository) CreatePosts(inputPost []*models.PostInput, dt string,
 		args = append(args, ip.Message, forumSlug, threadId, dt, ip.Author, ip.Parent, pq.Array(path))
 	}
 
-	tx, err := pr.db.BeginTx(context.Background(), &sql.TxOptions{})
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		pr.logger.Println(err.Error())
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
 	queryStr = strings.TrimSuffix(queryStr, ",")
 	queryStr += " RETURNING id, message, forum, thread, created, author, parent, isEdited;"
-	rows, err := tx.Query(queryStr, args...)
+	rows, err := tx.QueryContext(ctx, queryStr, args...)
 	if err != nil {
-		pr.logger.Println("before scan:", err.Error())
+		tx.Rollback()
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 	defer rows.Close()
@@ -124,12 +132,31 @@ func (pr *PostRepository) CreatePosts(inputPost []*models.PostInput, dt string,
 				return nil, myerr.RollbackError
 			}
 
-			pr.logger.Println(err.Error())
+			pr.logger.Error(ctx, err.Error())
 			return nil, myerr.InternalDbError
 		}
 
 		posts = append(posts, post)
 	}
+	rows.Close()
+
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+	}
+	err = pr.insertMentions(ctx, tx, posts, inputPost)
+	if err == nil {
+		err = pr.notify(ctx, tx, ids)
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+
+		pr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -139,10 +166,91 @@ func (pr *PostRepository) CreatePosts(inputPost []*models.PostInput, dt string,
 	return posts, nil
 }
 
-func (pr *PostRepository) CreatePost(inputPost *models.PostInput, dt string, forumSlug string, threadId int64) (*models.Post, error) {
-	tx, err := pr.db.BeginTx(context.Background(), &sql.TxOptions{})
+// insertMentions keeps the mentions of new posts that name existing users
+// and fills them in on the posts, which go in the order of the inputs.
+func (pr *PostRepository) insertMentions(ctx context.Context, tx *sql.Tx, posts []*models.Post, inputs []*models.PostInput) error {
+	ids := make([]int64, 0)
+	nicknames := make([]string, 0)
+	byId := make(map[int64]*models.Post, len(posts))
+	for ind, post := range posts {
+		byId[post.Id] = post
+		for _, nickname := range inputs[ind].Mentions {
+			ids = append(ids, post.Id)
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`INSERT INTO post_mentions (post, nickname)
+		 SELECT m.post, u.nickname
+		 FROM unnest($1::BIGINT[], $2::TEXT[]) AS m(post, nickname)
+		 JOIN users u ON u.nickname = m.nickname::CITEXT
+		 ON CONFLICT DO NOTHING
+		 RETURNING post, nickname::TEXT;`,
+		pq.Array(ids), pq.Array(nicknames))
 	if err != nil {
-		pr.logger.Println(err.Error())
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var nickname string
+		err = rows.Scan(&id, &nickname)
+		if err != nil {
+			return err
+		}
+		byId[id].Mentions = append(byId[id].Mentions, nickname)
+	}
+	for _, post := range posts {
+		sortMentions(post.Mentions)
+	}
+	return rows.Err()
+}
+
+// sortMentions puts mentions in the order the database lists them in.
+func sortMentions(mentions []string) {
+	sort.Slice(mentions, func(i, j int) bool {
+		return strings.ToLower(mentions[i]) < strings.ToLower(mentions[j])
+	})
+}
+
+// notify tells the authors of the parent posts, the mentioned users and the
+// subscribers of the thread about new posts. Everyone gets one notification
+// per post, a reply beating a mention beating a subscription, and nobody is
+// told about their own post.
+func (pr *PostRepository) notify(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO notifications (nickname, kind, post, thread, author, created)
+		 SELECT DISTINCT ON (target.nickname, p.id) target.nickname, target.kind, p.id, p.thread, p.author, p.created
+		 FROM posts p
+		 JOIN LATERAL (
+			SELECT pp.author AS nickname, 'reply' AS kind, 0 AS priority
+			FROM posts pp WHERE pp.id = p.parent
+			UNION ALL
+			SELECT m.nickname, 'mention' AS kind, 1 AS priority
+			FROM post_mentions m WHERE m.post = p.id
+			UNION ALL
+			SELECT s.nickname, 'thread' AS kind, 2 AS priority
+			FROM thread_subscriptions s WHERE s.thread = p.thread
+		 ) target ON target.nickname <> p.author
+		 WHERE p.id = ANY($1)
+		 ORDER BY target.nickname, p.id, target.priority;`,
+		pq.Array(ids))
+	return err
+}
+
+func (pr *PostRepository) CreatePost(ctx context.Context, inputPost *models.PostInput, dt string, forumSlug string, threadId int64) (*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "CreatePost", time.Now())
+
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
@@ -156,13 +264,13 @@ func (pr *PostRepository) CreatePost(inputPost *models.PostInput, dt string, for
 		RETURNING id, message, forum, thread, created, author, parent, isEdited;`
 	if inputPost.Parent == 0 {
 		queryStr = fmt.Sprintf(queryStr, "", "")
-		row = tx.QueryRow(queryStr, inputPost.Message, forumSlug, threadId, dt, inputPost.Author)
+		row = tx.QueryRowContext(ctx, queryStr, inputPost.Message, forumSlug, threadId, dt, inputPost.Author)
 	} else {
 		queryStr = fmt.Sprintf(
 			queryStr,
 			", parent",
 			", (SELECT (CASE WHEN EXISTS (SELECT id FROM posts WHERE id = $6 AND thread = $3) THEN $6 ELSE 0 END))")
-		row = tx.QueryRow(queryStr, inputPost.Message, forumSlug, threadId, dt, inputPost.Author, inputPost.Parent)
+		row = tx.QueryRowContext(ctx, queryStr, inputPost.Message, forumSlug, threadId, dt, inputPost.Author, inputPost.Parent)
 	}
 
 	post := &models.Post{}
@@ -173,17 +281,24 @@ func (pr *PostRepository) CreatePost(inputPost *models.PostInput, dt string, for
 			return nil, myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*\"parent\" violates.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ParentNotExist
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
+		return nil, dbErr
+	}
 
-		res, _ = regexp.Match(".*posts_author_fkey.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.UserNotExist
+	err = pr.insertMentions(ctx, tx, []*models.Post{post}, []*models.PostInput{inputPost})
+	if err == nil {
+		err = pr.notify(ctx, tx, []int64{post.Id})
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
 		}
 
-		pr.logger.Println(err.Error())
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
@@ -194,31 +309,41 @@ func (pr *PostRepository) CreatePost(inputPost *models.PostInput, dt string, for
 	return post, nil
 }
 
-func (pr *PostRepository) SelectThread(id int64, slug string) (int64, error) {
-	row := pr.db.QueryRow(
-		"SELECT id from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1",
+func (pr *PostRepository) SelectThread(ctx context.Context, id int64, slug string) (int64, error) {
+	defer metrics.ObserveQuery("posts", "SelectThread", time.Now())
+
+	row := pr.db.QueryRowContext(
+		ctx,
+		"SELECT id from threads WHERE (0 = $1 AND slug = $2 OR $2 = '' AND id = $1) AND status <> 'hidden'",
 		id, slug)
 	err := row.Scan(&id)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return 0, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		return 0, myerr.InternalDbError
+		return 0, dbErr
 	}
 	return id, nil
 }
 
-func (pr *PostRepository) SelectThreadsBySort(tq *models.ThreadsQuery) ([]*models.Post, error) {
+func (pr *PostRepository) SelectThreadsBySort(ctx context.Context, tq *models.ThreadsQuery) ([]*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "SelectThreadsBySort", time.Now())
+
 	var queryStr string
 	var counter uint = 2
 	var nums []interface{}
 	var args []interface{}
 	if tq.Sort == "flat" {
-		queryStr = `SELECT id, message, forum, thread, created, author, parent, isEdited 
+		queryStr = `SELECT id, message, forum, thread, created, author, parent, isEdited, isDeleted
 					FROM posts WHERE thread = $1 `
 		args = append(args, tq.ThreadId)
-		if tq.Since != 0 {
+		if tq.After != nil {
+			queryStr = queryStr + "AND (created, id) %s ($%d::timestamp with time zone, $%d) "
+			nums = append(nums, tq.Sign, counter, counter+1)
+			counter = counter + 2
+			args = append(args, tq.After.Created, tq.After.Id)
+		} else if tq.Since != 0 {
 			queryStr = queryStr + "AND id %s $%d "
 			nums = append(nums, tq.Sign, counter)
 			counter = counter + 1
@@ -229,21 +354,25 @@ func (pr *PostRepository) SelectThreadsBySort(tq *models.ThreadsQuery) ([]*model
 		args = append(args, tq.Limit)
 		queryStr = fmt.Sprintf(queryStr, nums...)
 	} else if tq.Sort == "tree" {
-		queryStr = `SELECT id, message, forum, thread, created, author, parent, isEdited 
+		queryStr = `SELECT id, message, forum, thread, created, author, parent, isEdited, isDeleted
 					FROM posts WHERE thread = $1 `
 		args = append(args, tq.ThreadId)
-		if tq.Since != 0 {
+		since := tq.Since
+		if tq.After != nil {
+			since = tq.After.Id
+		}
+		if since != 0 {
 			queryStr = queryStr + "AND array_append(path, id) %s (SELECT array_append(path, id) FROM posts WHERE id = $%d) "
 			nums = append(nums, tq.Sign, counter)
 			counter = counter + 1
-			args = append(args, tq.Since)
+			args = append(args, since)
 		}
 		queryStr = queryStr + "ORDER BY array_append(path, id) %s LIMIT $%d"
 		nums = append(nums, tq.Sorting, counter)
 		args = append(args, tq.Limit)
 		queryStr = fmt.Sprintf(queryStr, nums...)
 	} else if tq.Sort == "parent_tree" {
-		queryStr = `SELECT t.id, t.message, t.forum, t.thread, t.created, t.author, t.parent, t.isEdited 
+		queryStr = `SELECT t.id, t.message, t.forum, t.thread, t.created, t.author, t.parent, t.isEdited, t.isDeleted
 					FROM (SELECT *, CASE WHEN cardinality(path) = 0 THEN id ELSE path[1] END as rooot FROM posts) as t
 					WHERE t.rooot IN (
 						SELECT id FROM posts
@@ -254,7 +383,11 @@ func (pr *PostRepository) SelectThreadsBySort(tq *models.ThreadsQuery) ([]*model
 					ORDER BY t.rooot %s, array_append(path, id)`
 		args = append(args, tq.ThreadId)
 		s1 := ""
-		if tq.Since != 0 {
+		if tq.After != nil {
+			s1 = fmt.Sprintf("AND id %s $%d", tq.Sign, counter)
+			counter = counter + 1
+			args = append(args, tq.After.Id)
+		} else if tq.Since != 0 {
 			s1 = "AND id %s (SELECT CASE WHEN cardinality(path) = 0 THEN id ELSE path[1] END FROM posts WHERE id = $%d)"
 			s1 = fmt.Sprintf(s1, tq.Sign, counter)
 			counter = counter + 1
@@ -264,102 +397,172 @@ func (pr *PostRepository) SelectThreadsBySort(tq *models.ThreadsQuery) ([]*model
 		queryStr = fmt.Sprintf(queryStr, s1, tq.Sorting, counter, tq.Sorting)
 	}
 
-	rows, err := pr.db.Query(queryStr, args...)
+	rows, err := pr.db.QueryContext(ctx, queryStr, args...)
 	if err != nil {
-		pr.logger.Println(err.Error())
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
 	posts := make([]*models.Post, 0)
 	for rows.Next() {
 		post := &models.Post{}
-		err = rows.Scan(&post.Id, &post.Message, &post.Forum, &post.Thread, &post.Created, &post.Author, &post.Parent, &post.IsEdited)
+		err = rows.Scan(&post.Id, &post.Message, &post.Forum, &post.Thread, &post.Created, &post.Author, &post.Parent, &post.IsEdited, &post.IsDeleted)
 		if err != nil {
-			pr.logger.Println(err.Error())
+			rows.Close()
+			pr.logger.Error(ctx, err.Error())
 			return nil, myerr.InternalDbError
 		}
 		posts = append(posts, post)
 	}
+	rows.Close()
+
+	err = pr.selectPageMentions(ctx, posts)
+	if err != nil {
+		pr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
 	return posts, nil
 }
 
-func (pr *PostRepository) SelectPost(id int64) (*models.Post, error) {
+// selectPageMentions loads the mentions of a page of posts in one query
+// rather than one per post.
+func (pr *PostRepository) selectPageMentions(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(posts))
+	byId := make(map[int64]*models.Post, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+		byId[post.Id] = post
+	}
+
+	rows, err := pr.db.QueryContext(
+		ctx,
+		"SELECT post, nickname::TEXT FROM post_mentions WHERE post = ANY($1) ORDER BY post, nickname;",
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var nickname string
+		err = rows.Scan(&id, &nickname)
+		if err != nil {
+			return err
+		}
+		byId[id].Mentions = append(byId[id].Mentions, nickname)
+	}
+	return rows.Err()
+}
+
+func (pr *PostRepository) SelectPost(ctx context.Context, id int64) (*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "SelectPost", time.Now())
+
 	post := &models.Post{}
-	row := pr.db.QueryRow(
-		"SELECT id, parent, author, message, isEdited, forum, thread, created FROM posts WHERE id = $1;",
+	row := pr.db.QueryRowContext(
+		ctx,
+		`SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created,
+			ARRAY(SELECT m.nickname::TEXT FROM post_mentions m WHERE m.post = posts.id ORDER BY m.nickname)
+		 FROM posts
+		 WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM threads t WHERE t.id = posts.thread AND t.status = 'hidden');`,
 		id)
-	err := row.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created)
+	err := row.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted, &post.Forum, &post.Thread, &post.Created, pq.Array(&post.Mentions))
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.PostNotExist
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return post, nil
 }
 
-func (pr *PostRepository) SelectUser(nickname string) (*models.User, error) {
+func (pr *PostRepository) SelectUser(ctx context.Context, nickname string) (*models.User, error) {
+	defer metrics.ObserveQuery("posts", "SelectUser", time.Now())
+
 	user := &models.User{}
-	row := pr.db.QueryRow(
+	row := pr.db.QueryRowContext(
+		ctx,
 		"SELECT nickname, fullname, email, about FROM users WHERE nickname = $1;",
 		nickname)
 	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.UserNotExist
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return user, nil
 }
 
-func (pr *PostRepository) SelectThreadById(id int64) (*models.Thread, error) {
+func (pr *PostRepository) SelectThreadById(ctx context.Context, id int64) (*models.Thread, error) {
+	defer metrics.ObserveQuery("posts", "SelectThreadById", time.Now())
+
 	thread := &models.Thread{}
-	row := pr.db.QueryRow(
-		"SELECT id, slug, title, author, forum, message, votes, created FROM threads WHERE id = $1;",
+	row := pr.db.QueryRowContext(
+		ctx,
+		"SELECT id, slug, title, author, forum, message, votes, created, status, pinned FROM threads WHERE id = $1;",
 		id)
-	err := row.Scan(&thread.Id, &thread.Slug, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Created)
+	err := row.Scan(&thread.Id, &thread.Slug, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return thread, nil
 }
 
-func (pr *PostRepository) SelectForum(slug string) (*models.Forum, error) {
+func (pr *PostRepository) SelectForum(ctx context.Context, slug string) (*models.Forum, error) {
+	defer metrics.ObserveQuery("posts", "SelectForum", time.Now())
+
 	forum := &models.Forum{}
-	row := pr.db.QueryRow(
+	row := pr.db.QueryRowContext(
+		ctx,
 		"SELECT slug, title, author, posts, threads FROM forum WHERE slug = $1;",
 		slug)
 	err := row.Scan(&forum.Slug, &forum.Title, &forum.User, &forum.Posts, &forum.Threads)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ForumNotExist
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
 		}
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return forum, nil
 }
 
-func (pr *PostRepository) UpdatePost(postupdate *models.PostUpdate) (*models.Post, error) {
-	tx, err := pr.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (pr *PostRepository) UpdatePost(ctx context.Context, postupdate *models.PostUpdate) (*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "UpdatePost", time.Now())
+
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		pr.logger.Println(err.Error())
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
+	if postupdate.Editor != "" {
+		_, err = tx.ExecContext(ctx, "SELECT set_config('forum.editor', $1, TRUE);", postupdate.Editor)
+		if err != nil {
+			tx.Rollback()
+			pr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+	}
+
 	post := &models.Post{}
-	row := tx.QueryRow(
+	row := tx.QueryRowContext(
+		ctx,
 		`UPDATE posts SET 
 		 	message = CASE WHEN $2 = '' THEN message ELSE $2 END, 
 			isEdited = CASE WHEN $2 = '' THEN isEdited ELSE CASE WHEN message = $2 THEN isEdited ELSE $3 END END 
-		 WHERE id = $1
+		 WHERE id = $1 AND NOT isDeleted
 		 RETURNING id, parent, author, message, isEdited, forum, thread, created;`,
 		postupdate.Id, postupdate.Message, true)
 	err = row.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created)
@@ -368,12 +571,24 @@ func (pr *PostRepository) UpdatePost(postupdate *models.PostUpdate) (*models.Pos
 		if rollbackError != nil {
 			return nil, myerr.RollbackError
 		}
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.PostNotExist
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
+		}
+		if dbErr == myerr.PostNotExist {
+			return nil, pr.missingPostError(ctx, postupdate.Id)
+		}
+		return nil, dbErr
+	}
+
+	err = pr.updateMentions(ctx, tx, post, postupdate.Mentions)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
 		}
 
-		pr.logger.Println(err.Error())
+		pr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
@@ -383,3 +598,227 @@ func (pr *PostRepository) UpdatePost(postupdate *models.PostUpdate) (*models.Pos
 	}
 	return post, nil
 }
+
+// updateMentions swaps the mentions of an edited post for the given ones
+// and notifies the users mentioned for the first time. Nil leaves them
+// as they are.
+func (pr *PostRepository) updateMentions(ctx context.Context, tx *sql.Tx, post *models.Post, nicknames []string) error {
+	if nicknames == nil {
+		row := tx.QueryRowContext(
+			ctx,
+			"SELECT ARRAY(SELECT nickname::TEXT FROM post_mentions WHERE post = $1 ORDER BY nickname);",
+			post.Id)
+		return row.Scan(pq.Array(&post.Mentions))
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		`WITH stale AS (
+			DELETE FROM post_mentions WHERE post = $1 AND NOT nickname = ANY($2::CITEXT[])
+		 ), fresh AS (
+			INSERT INTO post_mentions (post, nickname)
+			SELECT $1, nickname FROM users WHERE nickname = ANY($2::CITEXT[])
+			ON CONFLICT DO NOTHING
+			RETURNING nickname
+		 ), notified AS (
+			INSERT INTO notifications (nickname, kind, post, thread, author)
+			SELECT nickname, 'mention', $1, $3, $4 FROM fresh WHERE nickname <> $4
+		 )
+		 SELECT ARRAY(SELECT nickname::TEXT FROM users WHERE nickname = ANY($2::CITEXT[]) ORDER BY nickname);`,
+		post.Id, pq.Array(nicknames), post.Thread, post.Author)
+	return row.Scan(pq.Array(&post.Mentions))
+}
+
+// missingPostError tells a post that never existed from a deleted one
+// after a write found no live row.
+func (pr *PostRepository) missingPostError(ctx context.Context, id int64) error {
+	var isDeleted bool
+	row := pr.db.QueryRowContext(ctx, "SELECT isDeleted FROM posts WHERE id = $1;", id)
+	err := row.Scan(&isDeleted)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	if isDeleted {
+		return myerr.PostDeleted
+	}
+	return myerr.PostNotExist
+}
+
+// DeletePost replaces the post with a tombstone. The revision of the
+// tombstone is recorded as the editor's.
+func (pr *PostRepository) DeletePost(ctx context.Context, id int64, editor string) (*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "DeletePost", time.Now())
+
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		pr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+
+	if editor != "" {
+		_, err = tx.ExecContext(ctx, "SELECT set_config('forum.editor', $1, TRUE);", editor)
+		if err != nil {
+			tx.Rollback()
+			pr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+	}
+
+	post := &models.Post{}
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE posts SET
+			message = $2,
+			isDeleted = TRUE
+		 WHERE id = $1
+		 RETURNING id, parent, author, message, isEdited, isDeleted, forum, thread, created;`,
+		id, models.PostTombstone)
+	err = row.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted, &post.Forum, &post.Thread, &post.Created)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	// the tombstone mentions nobody
+	_, err = tx.ExecContext(ctx, "DELETE FROM post_mentions WHERE post = $1;", id)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+		pr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, myerr.CommitError
+	}
+	return post, nil
+}
+
+func (pr *PostRepository) DeletePostTree(ctx context.Context, id int64) (int64, error) {
+	defer metrics.ObserveQuery("posts", "DeletePostTree", time.Now())
+
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		pr.logger.Error(ctx, err.Error())
+		return 0, myerr.InternalDbError
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM posts
+		 WHERE thread = (SELECT thread FROM posts WHERE id = $1)
+			AND (id = $1 OR $1 = ANY(path));`,
+		id)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return 0, myerr.RollbackError
+		}
+		pr.logger.Error(ctx, err.Error())
+		return 0, myerr.InternalDbError
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		pr.logger.Error(ctx, err.Error())
+		return 0, myerr.InternalDbError
+	}
+	if deleted == 0 {
+		tx.Rollback()
+		return 0, myerr.PostNotExist
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, myerr.CommitError
+	}
+	return deleted, nil
+}
+
+func (pr *PostRepository) SelectRevisions(ctx context.Context, id int64) ([]*models.PostRevision, error) {
+	defer metrics.ObserveQuery("posts", "SelectRevisions", time.Now())
+
+	rows, err := pr.db.QueryContext(
+		ctx,
+		"SELECT rev, message, editor, created FROM post_revisions WHERE post = $1 ORDER BY rev;",
+		id)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	revisions := make([]*models.PostRevision, 0)
+	for rows.Next() {
+		revision := &models.PostRevision{}
+		err = rows.Scan(&revision.Rev, &revision.Message, &revision.Editor, &revision.Created)
+		if err != nil {
+			pr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// SelectMentions lists the posts mentioning a user, paged by post id.
+func (pr *PostRepository) SelectMentions(ctx context.Context, mq *models.MentionsQuery) ([]*models.Post, error) {
+	defer metrics.ObserveQuery("posts", "SelectMentions", time.Now())
+
+	row := pr.db.QueryRowContext(ctx, "SELECT nickname FROM users WHERE nickname = $1;", mq.Nickname)
+	err := row.Scan(&mq.Nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			pr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	queryStr := fmt.Sprintf(`
+		SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.isDeleted, p.forum, p.thread, p.created,
+			ARRAY(SELECT m.nickname::TEXT FROM post_mentions m WHERE m.post = p.id ORDER BY m.nickname)
+		FROM post_mentions pm
+		JOIN posts p ON p.id = pm.post
+		WHERE pm.nickname = $1 AND ($2 = 0 OR pm.post %s $2)
+			AND NOT EXISTS (SELECT 1 FROM threads t WHERE t.id = p.thread AND t.status = 'hidden')
+		ORDER BY pm.post %s
+		LIMIT $3;`,
+		mq.Sign, mq.Sorting)
+	rows, err := pr.db.QueryContext(ctx, queryStr, mq.Nickname, mq.Since, mq.Limit)
+	if err != nil {
+		pr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	posts := make([]*models.Post, 0)
+	for rows.Next() {
+		post := &models.Post{}
+		err = rows.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted, &post.Forum, &post.Thread, &post.Created, pq.Array(&post.Mentions))
+		if err != nil {
+			pr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
@@ -1,10 +1,19 @@
 package posts
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type PostUsecase interface {
-	CreatePostsBySlugOrId(slug string, id int64, postsInput []*models.PostInput) ([]*models.Post, error)
-	GetPostsRec(tq *models.ThreadsQuery) ([]*models.Post, error)
-	GetInfo(pq *models.PostQuery) (map[string]interface{}, error)
-	UpdatePost(pu *models.PostUpdate) (*models.Post, error)
+	CreatePostsBySlugOrId(ctx context.Context, slug string, id int64, postsInput []*models.PostInput) ([]*models.Post, error)
+	GetPostsRec(ctx context.Context, tq *models.ThreadsQuery) ([]*models.Post, error)
+	GetInfo(ctx context.Context, pq *models.PostQuery) (map[string]interface{}, error)
+	UpdatePost(ctx context.Context, pu *models.PostUpdate) (*models.Post, error)
+	DeletePost(ctx context.Context, id int64) (*models.Post, error)
+	DeletePostTree(ctx context.Context, id int64) (int64, error)
+	GetHistory(ctx context.Context, id int64) ([]*models.PostRevision, error)
+	GetRevision(ctx context.Context, id int64, rev int64) (*models.PostRevision, error)
+	GetDiff(ctx context.Context, id int64, from int64, to int64) (*models.PostDiff, error)
+	GetMentions(ctx context.Context, mq *models.MentionsQuery) ([]*models.Post, error)
 }
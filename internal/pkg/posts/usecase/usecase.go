@@ -1,24 +1,57 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/bans"
+	"forum/internal/pkg/diff"
+	"forum/internal/pkg/filters"
 	"forum/internal/pkg/posts"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// maxMentions caps the nicknames looked up for a single post.
+const maxMentions = 50
+
+// mentionPattern matches @nickname unless it follows a word character, so
+// e-mail addresses are not taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@(\w[\w.]*)`)
+
 type PostUsecase struct {
-	repo posts.PostRepository
+	repo    posts.PostRepository
+	roles   auth.RoleResolver
+	bans    bans.BanChecker
+	filters filters.Screener
 }
 
-func NewPostUsecase(repo posts.PostRepository) posts.PostUsecase {
+func NewPostUsecase(repo posts.PostRepository, roles auth.RoleResolver, bans bans.BanChecker, filters filters.Screener) posts.PostUsecase {
 	return &PostUsecase{
-		repo: repo,
+		repo:    repo,
+		roles:   roles,
+		bans:    bans,
+		filters: filters,
 	}
 }
 
-func (pu *PostUsecase) CreatePostsBySlugOrId(slug string, id int64, postsInput []*models.PostInput) ([]*models.Post, error) {
-	forumSlug, threadId, err := pu.repo.SelectFormSlugByThread(slug, id)
+// CreatePostsBySlugOrId posts on behalf of the authors, who default to the
+// authenticated user and must all be someone the request may act as.
+func (pu *PostUsecase) CreatePostsBySlugOrId(ctx context.Context, slug string, id int64, postsInput []*models.PostInput) ([]*models.Post, error) {
+	for _, ip := range postsInput {
+		if ip.Author == "" {
+			ip.Author = auth.Nickname(ctx)
+		}
+		err := auth.Authorize(ctx, ip.Author)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	forumSlug, threadId, status, err := pu.repo.SelectFormSlugByThread(ctx, slug, id)
 	switch err {
 	case nil:
 		// skip this state
@@ -27,30 +60,60 @@ func (pu *PostUsecase) CreatePostsBySlugOrId(slug string, id int64, postsInput [
 	default:
 		return nil, err
 	}
+	if status != models.ThreadOpen {
+		return nil, myerr.ThreadClosed
+	}
 
 	if len(postsInput) == 0 {
 		return make([]*models.Post, 0), nil
 	}
 
+	authors := make([]string, 0, len(postsInput))
+	texts := make([]*string, 0, len(postsInput))
+	for _, ip := range postsInput {
+		authors = append(authors, ip.Author)
+		texts = append(texts, &ip.Message)
+	}
+	err = pu.bans.CheckAllowed(ctx, forumSlug, authors...)
+	if err != nil {
+		return nil, err
+	}
+
+	// a post held for review holds the whole batch
+	err = pu.filters.Screen(ctx, &filters.Content{
+		Kind:    models.HeldPosts,
+		Forum:   forumSlug,
+		Thread:  threadId,
+		Texts:   texts,
+		Authors: authors,
+		Payload: postsInput,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range postsInput {
+		ip.Mentions = parseMentions(ip.Message)
+	}
+
 	dt := time.Now().Format(models.Layout)
-	posts, err := pu.repo.CreatePosts(postsInput, dt, forumSlug, threadId)
+	posts, err := pu.repo.CreatePosts(ctx, postsInput, dt, forumSlug, threadId)
 
 	return posts, err
 }
 
-func (pu *PostUsecase) GetPostsRec(tq *models.ThreadsQuery) ([]*models.Post, error) {
-	id, err := pu.repo.SelectThread(tq.ThreadId, tq.ThreadSlug)
+func (pu *PostUsecase) GetPostsRec(ctx context.Context, tq *models.ThreadsQuery) ([]*models.Post, error) {
+	id, err := pu.repo.SelectThread(ctx, tq.ThreadId, tq.ThreadSlug)
 	if err != nil {
 		return nil, err
 	}
 
 	tq.ThreadId = id
-	posts, err := pu.repo.SelectThreadsBySort(tq)
+	posts, err := pu.repo.SelectThreadsBySort(ctx, tq)
 	return posts, err
 }
 
-func (pu *PostUsecase) GetInfo(pq *models.PostQuery) (map[string]interface{}, error) {
-	post, err := pu.repo.SelectPost(pq.PostId)
+func (pu *PostUsecase) GetInfo(ctx context.Context, pq *models.PostQuery) (map[string]interface{}, error) {
+	post, err := pu.repo.SelectPost(ctx, pq.PostId)
 	if err != nil {
 		return nil, err
 	}
@@ -61,19 +124,19 @@ func (pu *PostUsecase) GetInfo(pq *models.PostQuery) (map[string]interface{}, er
 	for _, val := range pq.Related {
 		switch val {
 		case "user":
-			user, err := pu.repo.SelectUser(post.Author)
+			user, err := pu.repo.SelectUser(ctx, post.Author)
 			if err != nil {
 				return nil, err
 			}
 			info["author"] = user
 		case "forum":
-			forum, err := pu.repo.SelectForum(post.Forum)
+			forum, err := pu.repo.SelectForum(ctx, post.Forum)
 			if err != nil {
 				return nil, err
 			}
 			info["forum"] = forum
 		case "thread":
-			thread, err := pu.repo.SelectThreadById(post.Thread)
+			thread, err := pu.repo.SelectThreadById(ctx, post.Thread)
 			if err != nil {
 				return nil, err
 			}
@@ -83,7 +146,163 @@ func (pu *PostUsecase) GetInfo(pq *models.PostQuery) (map[string]interface{}, er
 	return info, nil
 }
 
-func (pu *PostUsecase) UpdatePost(postupdate *models.PostUpdate) (*models.Post, error) {
-	post, err := pu.repo.UpdatePost(postupdate)
+// UpdatePost records the authenticated user as the editor, overriding the
+// one named in the body.
+func (pu *PostUsecase) UpdatePost(ctx context.Context, postupdate *models.PostUpdate) (*models.Post, error) {
+	err := pu.authorizeAuthor(ctx, postupdate.Id)
+	if err != nil {
+		return nil, err
+	}
+	if nickname := auth.Nickname(ctx); nickname != "" {
+		postupdate.Editor = nickname
+	}
+
+	if postupdate.Message != "" {
+		postupdate.Mentions = parseMentions(postupdate.Message)
+	}
+	post, err := pu.repo.UpdatePost(ctx, postupdate)
+	return post, err
+}
+
+func (pu *PostUsecase) DeletePost(ctx context.Context, id int64) (*models.Post, error) {
+	err := pu.authorizeAuthor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := pu.repo.DeletePost(ctx, id, auth.Nickname(ctx))
 	return post, err
 }
+
+// authorizeAuthor checks that the request may act as the author of the
+// post or moderates its forum, sparing the lookup when nobody needs to be
+// checked.
+func (pu *PostUsecase) authorizeAuthor(ctx context.Context, id int64) error {
+	if auth.IsAdmin(ctx) {
+		return nil
+	}
+	if auth.Nickname(ctx) == "" {
+		return myerr.Unauthorized
+	}
+
+	post, err := pu.repo.SelectPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	return auth.AuthorizeAuthor(ctx, pu.roles, post.Forum, post.Author)
+}
+
+// DeletePostTree removes a post with all of its replies for good and is
+// reserved to admins and the moderators of its forum.
+func (pu *PostUsecase) DeletePostTree(ctx context.Context, id int64) (int64, error) {
+	if !auth.IsAdmin(ctx) {
+		post, err := pu.repo.SelectPost(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		err = auth.AuthorizeModerator(ctx, pu.roles, post.Forum)
+		if err != nil {
+			return 0, err
+		}
+	}
+	deleted, err := pu.repo.DeletePostTree(ctx, id)
+	return deleted, err
+}
+
+// GetHistory lists the revisions of a post, oldest first. A post that was
+// never edited has its current text as the only revision. The history of
+// a deleted post is gone along with its text.
+func (pu *PostUsecase) GetHistory(ctx context.Context, id int64) ([]*models.PostRevision, error) {
+	post, err := pu.repo.SelectPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsDeleted {
+		return nil, myerr.PostDeleted
+	}
+
+	revisions, err := pu.repo.SelectRevisions(ctx, id)
+	if err != nil || len(revisions) != 0 {
+		return revisions, err
+	}
+	return []*models.PostRevision{{
+		Rev:     1,
+		Message: post.Message,
+		Editor:  post.Author,
+		Created: post.Created,
+	}}, nil
+}
+
+func (pu *PostUsecase) GetRevision(ctx context.Context, id int64, rev int64) (*models.PostRevision, error) {
+	revisions, err := pu.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return findRevision(revisions, rev)
+}
+
+// GetDiff compares two revisions. Zero stands for the first revision as
+// from and for the latest one as to.
+func (pu *PostUsecase) GetDiff(ctx context.Context, id int64, from int64, to int64) (*models.PostDiff, error) {
+	revisions, err := pu.GetHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = revisions[0].Rev
+	}
+	if to == 0 {
+		to = revisions[len(revisions)-1].Rev
+	}
+
+	fromRev, err := findRevision(revisions, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := findRevision(revisions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PostDiff{
+		Post: id,
+		From: from,
+		To:   to,
+		Diff: diff.Unified(fmt.Sprintf("rev %d", from), fmt.Sprintf("rev %d", to), fromRev.Message, toRev.Message),
+	}, nil
+}
+
+func findRevision(revisions []*models.PostRevision, rev int64) (*models.PostRevision, error) {
+	for _, revision := range revisions {
+		if revision.Rev == rev {
+			return revision, nil
+		}
+	}
+	return nil, myerr.RevisionNotExist
+}
+
+func (pu *PostUsecase) GetMentions(ctx context.Context, mq *models.MentionsQuery) ([]*models.Post, error) {
+	posts, err := pu.repo.SelectMentions(ctx, mq)
+	return posts, err
+}
+
+// parseMentions lists the distinct nicknames mentioned in a message in
+// order of appearance. A trailing dot may end the sentence rather than the
+// nickname, so both spellings are kept and the users table decides.
+func parseMentions(message string) []string {
+	seen := make(map[string]bool)
+	mentions := make([]string, 0)
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		for _, nickname := range []string{match[1], strings.TrimRight(match[1], ".")} {
+			key := strings.ToLower(nickname)
+			if nickname == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			mentions = append(mentions, nickname)
+		}
+	}
+	if len(mentions) > maxMentions {
+		mentions = mentions[:maxMentions]
+	}
+	return mentions
+}
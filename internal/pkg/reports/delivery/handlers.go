@@ -0,0 +1,140 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/reports"
+	"forum/internal/pkg/response"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportDelivery struct {
+	reportUsecase reports.ReportUsecase
+}
+
+func NewReportDelivery(reportUsecase reports.ReportUsecase) *ReportDelivery {
+	return &ReportDelivery{
+		reportUsecase: reportUsecase,
+	}
+}
+
+func (rd *ReportDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/post/{id:[0-9]+}/report", rd.ReportPostHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/thread/{slug_or_id}/report", rd.ReportThreadHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/reports", rd.GetReportsHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/reports/{id:[0-9]+}/resolve", rd.ResolveHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/reports/{id:[0-9]+}/dismiss", rd.DismissHandler).Methods(http.MethodPost, http.MethodOptions)
+}
+
+// readBody decodes an optional JSON body into v.
+func readBody(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return myerr.InvalidBody
+	}
+	if len(buf) != 0 && json.Unmarshal(buf, v) != nil {
+		return myerr.InvalidBody
+	}
+	return nil
+}
+
+func (rd *ReportDelivery) ReportPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	input := &models.ReportInput{}
+	err := readBody(r, input)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	report, err := rd.reportUsecase.ReportPost(r.Context(), id, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, report)
+	case errors.Is(err, myerr.PostNotExist):
+		response.Error(w, r, myerr.PostNotExist.WithMessage("Can't find post with id: %d", id))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", input.Reporter))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (rd *ReportDelivery) ReportThreadHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug_or_id"]
+	id, err := strconv.ParseInt(slug, 10, 64)
+	if err == nil {
+		slug = ""
+	} else {
+		id = 0
+	}
+
+	input := &models.ReportInput{}
+	err = readBody(r, input)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	report, err := rd.reportUsecase.ReportThread(r.Context(), slug, id, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, report)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", input.Reporter))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+// GetReportsHandler serves the moderation queue, filtered by ?status=,
+// ?category= and ?kind=.
+func (rd *ReportDelivery) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
+	rq := models.NewReportsQuery(mux.Vars(r), r.URL.Query())
+	reports, err := rd.reportUsecase.GetReports(r.Context(), rq)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, reports)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", rq.Forum))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+// ResolveHandler upholds the report; {"hide": true} also hides the content.
+func (rd *ReportDelivery) ResolveHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	resolution := &models.ReportResolution{}
+	err := readBody(r, resolution)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	report, err := rd.reportUsecase.Resolve(r.Context(), mux.Vars(r)["slug"], id, resolution)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+func (rd *ReportDelivery) DismissHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	report, err := rd.reportUsecase.Dismiss(r.Context(), mux.Vars(r)["slug"], id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,13 @@
+package reports
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type ReportRepository interface {
+	InsertPostReport(ctx context.Context, id int64, report *models.Report) error
+	InsertThreadReport(ctx context.Context, slug string, id int64, report *models.Report) error
+	SelectReports(ctx context.Context, rq *models.ReportsQuery) ([]*models.Report, error)
+	UpdateReport(ctx context.Context, forum string, id int64, resolver string, status string, hide bool) (*models.Report, error)
+}
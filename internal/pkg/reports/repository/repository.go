@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/reports"
+	"time"
+)
+
+const reportColumns = "id, kind, COALESCE(post, 0), thread, forum, reporter, category, comment, status, resolver, resolved, created"
+
+type ReportRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewReportRepository(db *sql.DB, lg *logger.Logger) reports.ReportRepository {
+	return &ReportRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReport(row scanner, report *models.Report) error {
+	var resolved sql.NullString
+	err := row.Scan(&report.Id, &report.Kind, &report.Post, &report.Thread, &report.Forum, &report.Reporter,
+		&report.Category, &report.Comment, &report.Status, &report.Resolver, &resolved, &report.Created)
+	report.Resolved = resolved.String
+	return err
+}
+
+// InsertPostReport files the report against the post and the thread and
+// forum it belongs to.
+func (rr *ReportRepository) InsertPostReport(ctx context.Context, id int64, report *models.Report) error {
+	defer metrics.ObserveQuery("reports", "InsertPostReport", time.Now())
+
+	row := rr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO reports (kind, post, thread, forum, reporter, category, comment)
+		 SELECT $2, p.id, p.thread, p.forum, COALESCE((SELECT nickname FROM users WHERE nickname = $3), $3), $4, $5
+		 FROM posts p
+		 WHERE p.id = $1
+		 RETURNING `+reportColumns+`;`,
+		id, models.ReportPost, report.Reporter, report.Category, report.Comment)
+	err := scanReport(row, report)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.PostNotExist)
+		if !known {
+			rr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (rr *ReportRepository) InsertThreadReport(ctx context.Context, slug string, id int64, report *models.Report) error {
+	defer metrics.ObserveQuery("reports", "InsertThreadReport", time.Now())
+
+	row := rr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO reports (kind, thread, forum, reporter, category, comment)
+		 SELECT $3, t.id, t.forum, COALESCE((SELECT nickname FROM users WHERE nickname = $4), $4), $5, $6
+		 FROM threads t
+		 WHERE 0 = $1 AND t.slug = $2 OR $2 = '' AND t.id = $1
+		 RETURNING `+reportColumns+`;`,
+		id, slug, models.ReportThread, report.Reporter, report.Category, report.Comment)
+	err := scanReport(row, report)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			rr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+// SelectReports pages through the forum's queue by id. Empty filters match
+// any report.
+func (rr *ReportRepository) SelectReports(ctx context.Context, rq *models.ReportsQuery) ([]*models.Report, error) {
+	defer metrics.ObserveQuery("reports", "SelectReports", time.Now())
+
+	row := rr.db.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1;", rq.Forum)
+	err := row.Scan(&rq.Forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			rr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	query := fmt.Sprintf(
+		`SELECT `+reportColumns+` FROM reports
+		 WHERE forum = $1
+			AND ($2 = '' OR status = $2)
+			AND ($3 = '' OR category = $3)
+			AND ($4 = '' OR kind = $4)
+			AND ($5 = 0 OR id %s $5)
+		 ORDER BY id %s
+		 LIMIT $6;`,
+		rq.Sign, rq.Sorting)
+	rows, err := rr.db.QueryContext(ctx, query, rq.Forum, rq.Status, rq.Category, rq.Kind, rq.Since, rq.Limit)
+	if err != nil {
+		rr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	reports := make([]*models.Report, 0)
+	for rows.Next() {
+		report := &models.Report{}
+		err = scanReport(rows, report)
+		if err != nil {
+			rr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// UpdateReport closes an open report of the forum with the given status.
+// Hiding tombstones the reported post or hides the reported thread in
+// the same transaction and closes the other open reports on that content.
+func (rr *ReportRepository) UpdateReport(ctx context.Context, forum string, id int64, resolver string, status string, hide bool) (*models.Report, error) {
+	defer metrics.ObserveQuery("reports", "UpdateReport", time.Now())
+
+	tx, err := rr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		rr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+
+	report := &models.Report{}
+	row := tx.QueryRowContext(ctx, "SELECT "+reportColumns+" FROM reports WHERE id = $1 AND forum = $2 FOR UPDATE;", id, forum)
+	err = scanReport(row, report)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+		dbErr, known := myerr.FromDb(err, myerr.ReportNotExist)
+		if !known {
+			rr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	if report.Status != models.ReportOpen {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+		return nil, myerr.ReportAlreadyHandled
+	}
+
+	if hide {
+		err = rr.hide(ctx, tx, report)
+		if err != nil {
+			rollbackError := tx.Rollback()
+			if rollbackError != nil {
+				return nil, myerr.RollbackError
+			}
+			rr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+	}
+
+	row = tx.QueryRowContext(
+		ctx,
+		`WITH closed AS (
+			UPDATE reports SET status = $2, resolver = $3, resolved = NOW()
+			WHERE id = $1
+				OR ($4 AND status = 'open' AND kind = $5 AND thread = $6 AND COALESCE(post, 0) = $7)
+			RETURNING `+reportColumns+`
+		 )
+		 SELECT * FROM closed WHERE id = $1;`,
+		id, status, resolver, hide, report.Kind, report.Thread, report.Post)
+	err = scanReport(row, report)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, myerr.RollbackError
+		}
+		rr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, myerr.CommitError
+	}
+	return report, nil
+}
+
+// hide takes the reported content out of every read. A hidden post keeps
+// no trace of its text: its history is purged and the events and webhook
+// payloads that carried it get the tombstone instead.
+func (rr *ReportRepository) hide(ctx context.Context, tx *sql.Tx, report *models.Report) error {
+	if report.Kind == models.ReportThread {
+		_, err := tx.ExecContext(ctx, "UPDATE threads SET status = $2 WHERE id = $1;", report.Thread, models.ThreadHidden)
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "UPDATE posts SET message = $2, isDeleted = TRUE WHERE id = $1;", report.Post, models.PostTombstone)
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM post_revisions WHERE post = $1;",
+		"DELETE FROM post_mentions WHERE post = $1;",
+	} {
+		_, err = tx.ExecContext(ctx, query, report.Post)
+		if err != nil {
+			return err
+		}
+	}
+	for _, table := range []string{"thread_events", "webhook_outbox"} {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE `+table+`
+			 SET payload = (payload::JSONB || jsonb_build_object('message', $3::TEXT, 'isDeleted', TRUE))::JSON
+			 WHERE thread = $1 AND kind IN ($4, $5) AND payload->>'id' = $2::TEXT;`,
+			report.Thread, report.Post, models.PostTombstone, models.EventPost, models.EventPostEdit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
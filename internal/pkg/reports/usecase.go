@@ -0,0 +1,14 @@
+package reports
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type ReportUsecase interface {
+	ReportPost(ctx context.Context, id int64, input *models.ReportInput) (*models.Report, error)
+	ReportThread(ctx context.Context, slug string, id int64, input *models.ReportInput) (*models.Report, error)
+	GetReports(ctx context.Context, rq *models.ReportsQuery) ([]*models.Report, error)
+	Resolve(ctx context.Context, forum string, id int64, resolution *models.ReportResolution) (*models.Report, error)
+	Dismiss(ctx context.Context, forum string, id int64) (*models.Report, error)
+}
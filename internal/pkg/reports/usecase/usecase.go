@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/reports"
+)
+
+type ReportUsecase struct {
+	repo  reports.ReportRepository
+	roles auth.RoleResolver
+}
+
+func NewReportUsecase(repo reports.ReportRepository, roles auth.RoleResolver) reports.ReportUsecase {
+	return &ReportUsecase{
+		repo:  repo,
+		roles: roles,
+	}
+}
+
+// ReportPost files the report as the authenticated user when the body
+// names nobody.
+func (ru *ReportUsecase) ReportPost(ctx context.Context, id int64, input *models.ReportInput) (*models.Report, error) {
+	report, err := newReport(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ru.repo.InsertPostReport(ctx, id, report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (ru *ReportUsecase) ReportThread(ctx context.Context, slug string, id int64, input *models.ReportInput) (*models.Report, error) {
+	report, err := newReport(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ru.repo.InsertThreadReport(ctx, slug, id, report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func newReport(ctx context.Context, input *models.ReportInput) (*models.Report, error) {
+	if input.Reporter == "" {
+		input.Reporter = auth.Nickname(ctx)
+	}
+	err := auth.Authorize(ctx, input.Reporter)
+	if err != nil {
+		return nil, err
+	}
+	if input.Reporter == "" || !models.ValidReportCategory(input.Category) {
+		return nil, myerr.InvalidReport
+	}
+
+	return &models.Report{
+		Reporter: input.Reporter,
+		Category: input.Category,
+		Comment:  input.Comment,
+	}, nil
+}
+
+func (ru *ReportUsecase) GetReports(ctx context.Context, rq *models.ReportsQuery) ([]*models.Report, error) {
+	err := auth.AuthorizeModerator(ctx, ru.roles, rq.Forum)
+	if err != nil {
+		return nil, err
+	}
+	return ru.repo.SelectReports(ctx, rq)
+}
+
+func (ru *ReportUsecase) Resolve(ctx context.Context, forum string, id int64, resolution *models.ReportResolution) (*models.Report, error) {
+	err := auth.AuthorizeModerator(ctx, ru.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return ru.repo.UpdateReport(ctx, forum, id, auth.Nickname(ctx), models.ReportResolved, resolution.Hide)
+}
+
+func (ru *ReportUsecase) Dismiss(ctx context.Context, forum string, id int64) (*models.Report, error) {
+	err := auth.AuthorizeModerator(ctx, ru.roles, forum)
+	if err != nil {
+		return nil, err
+	}
+	return ru.repo.UpdateReport(ctx, forum, id, auth.Nickname(ctx), models.ReportDismissed, false)
+}
@@ -0,0 +1,51 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"net/http"
+	"net/url"
+)
+
+var internalError = models.Error{
+	Code:    "internal_error",
+	Message: "internal server error",
+}
+
+func JSON(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	w.Write(models.ToBytes(body))
+}
+
+// Error renders err as the error envelope. A CustomError, wrapped or not,
+// defines the status and the code; any other error is logged and answered
+// with an opaque 500 so driver messages never reach the client. The log
+// line goes through the request's logger and carries its id.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	var ce myerr.CustomError
+	if !errors.As(err, &ce) {
+		logger.FromContext(r.Context()).Error(r.Context(), "request failed", "error", err)
+		JSON(w, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	if ce.Code >= http.StatusInternalServerError && ce.Code != http.StatusServiceUnavailable && ce.Code != http.StatusGatewayTimeout {
+		JSON(w, ce.Code, internalError)
+		return
+	}
+
+	JSON(w, ce.Code, models.Error{Code: ce.Reason, Message: ce.Message})
+}
+
+// NextLink advertises the next page of a list in a Link header. It has to
+// be called before the body is written.
+func NextLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	query := r.URL.Query()
+	query.Del("since")
+	query.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
@@ -0,0 +1,34 @@
+package delivery
+
+import (
+	"forum/internal/models"
+	"forum/internal/pkg/response"
+	"forum/internal/pkg/search"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type SearchDelivery struct {
+	searchUsecase search.SearchUsecase
+}
+
+func NewSearchDelivery(searchUsecase search.SearchUsecase) *SearchDelivery {
+	return &SearchDelivery{
+		searchUsecase: searchUsecase,
+	}
+}
+
+func (sd *SearchDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/search", sd.SearchHandler).Methods(http.MethodGet, http.MethodOptions)
+}
+
+func (sd *SearchDelivery) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := models.NewSearchQuery(r.URL.Query())
+	results, err := sd.searchUsecase.Search(r.Context(), sq)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,10 @@
+package search
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type SearchRepository interface {
+	Search(ctx context.Context, tsQuery string, sq *models.SearchQuery) ([]*models.SearchResult, error)
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/search"
+	"html"
+	"strings"
+	"time"
+)
+
+// ts_headline marks the hits with private use characters, which the text
+// is stripped of beforehand. They become <b></b> only once the text around
+// them is escaped, so a snippet is safe to render as HTML.
+const (
+	startSel = "\uE000"
+	stopSel  = "\uE001"
+)
+
+// headlineOptions keeps snippets short enough for a result list.
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", MaxWords=35, MinWords=15, MaxFragments=2"
+
+var highlighter = strings.NewReplacer(startSel, "<b>", stopSel, "</b>")
+
+func highlight(headline string) string {
+	return highlighter.Replace(html.EscapeString(headline))
+}
+
+type SearchRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewSearchRepository(db *sql.DB, lg *logger.Logger) search.SearchRepository {
+	return &SearchRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+// Search ranks visible threads and live posts together. Headlines are the costly
+// part, so they are built only for the rows that make the page.
+func (sr *SearchRepository) Search(ctx context.Context, tsQuery string, sq *models.SearchQuery) ([]*models.SearchResult, error) {
+	defer metrics.ObserveQuery("search", "Search", time.Now())
+
+	queryStr := fmt.Sprintf(`
+		WITH q AS (SELECT to_tsquery('simple', $1) AS query),
+		hits AS (
+			SELECT 'thread' AS kind, t.id, t.id AS thread, t.forum, t.author, t.title,
+				t.message AS body, ts_rank(t.search_tsv, q.query) AS rank, t.created
+			FROM threads t, q
+			WHERE t.search_tsv @@ q.query AND t.status <> 'hidden'
+				AND ($2::citext = '' OR t.forum = $2::citext)
+				AND ($3::citext = '' OR t.author = $3::citext)
+				AND ($4::timestamptz IS NULL OR t.created %[1]s $4::timestamptz)
+			UNION ALL
+			SELECT 'post' AS kind, p.id, p.thread, p.forum, p.author, '' AS title,
+				p.message AS body, ts_rank(p.message_tsv, q.query) AS rank, p.created
+			FROM posts p, q
+			WHERE p.message_tsv @@ q.query AND NOT p.isDeleted
+				AND NOT EXISTS (SELECT 1 FROM threads t WHERE t.id = p.thread AND t.status = 'hidden')
+				AND ($2::citext = '' OR p.forum = $2::citext)
+				AND ($3::citext = '' OR p.author = $3::citext)
+				AND ($4::timestamptz IS NULL OR p.created %[1]s $4::timestamptz)
+			ORDER BY rank DESC, created %[2]s, id
+			LIMIT $5
+		)
+		SELECT hits.kind, hits.id, hits.thread, hits.forum, hits.author, hits.title,
+			ts_headline('simple', translate(hits.body, $7, ''), q.query, $6), hits.rank, hits.created
+		FROM hits, q
+		ORDER BY hits.rank DESC, hits.created %[2]s, hits.id;`,
+		sq.Sign, sq.Sorting)
+
+	var since interface{}
+	if sq.Since != "" {
+		since = sq.Since
+	}
+	rows, err := sr.db.QueryContext(ctx, queryStr, tsQuery, sq.Forum, sq.Author, since, sq.Limit, headlineOptions, startSel+stopSel)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			sr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	results := make([]*models.SearchResult, 0)
+	for rows.Next() {
+		result := &models.SearchResult{}
+		err = rows.Scan(&result.Type, &result.Id, &result.Thread, &result.Forum, &result.Author,
+			&result.Title, &result.Snippet, &result.Rank, &result.Created)
+		if err != nil {
+			sr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		result.Snippet = highlight(result.Snippet)
+		results = append(results, result)
+	}
+	return results, nil
+}
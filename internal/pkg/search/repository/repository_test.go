@@ -0,0 +1,21 @@
+package repository
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{"plain text", "plain text"},
+		{"a " + startSel + "hit" + stopSel + " here", "a <b>hit</b> here"},
+		{"<script>alert(1)</script> " + startSel + "hit" + stopSel, "&lt;script&gt;alert(1)&lt;/script&gt; <b>hit</b>"},
+		{startSel + "<b>" + stopSel + " & \"q\"", "<b>&lt;b&gt;</b> &amp; &#34;q&#34;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlight(tt.headline); got != tt.want {
+			t.Errorf("highlight(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package search
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type SearchUsecase interface {
+	Search(ctx context.Context, sq *models.SearchQuery) ([]*models.SearchResult, error)
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/search"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type SearchUsecase struct {
+	repo search.SearchRepository
+}
+
+func NewSearchUsecase(repo search.SearchRepository) search.SearchUsecase {
+	return &SearchUsecase{
+		repo: repo,
+	}
+}
+
+func (su *SearchUsecase) Search(ctx context.Context, sq *models.SearchQuery) ([]*models.SearchResult, error) {
+	tsQuery := buildTsQuery(sq.Text)
+	if tsQuery == "" {
+		return nil, myerr.InvalidSearchQuery
+	}
+	if sq.Since != "" {
+		if _, err := time.Parse(time.RFC3339, sq.Since); err != nil {
+			return nil, myerr.InvalidSearchQuery.WithMessage("since %q is not an RFC 3339 time", sq.Since)
+		}
+	}
+
+	results, err := su.repo.Search(ctx, tsQuery, sq)
+	return results, err
+}
+
+// buildTsQuery turns the user's query into to_tsquery syntax. Every term
+// must match; "quoted words" must follow each other and a trailing *
+// matches by prefix. Everything but letters and digits separates words, so
+// no tsquery operator ever reaches the database from the user.
+func buildTsQuery(text string) string {
+	terms := make([]string, 0)
+	for i, part := range strings.Split(text, `"`) {
+		// odd parts are inside quotes
+		if i%2 == 1 {
+			if phrase := strings.Join(lexemes(part), " <-> "); phrase != "" {
+				terms = append(terms, "("+phrase+")")
+			}
+			continue
+		}
+		terms = append(terms, lexemes(part)...)
+	}
+	return strings.Join(terms, " & ")
+}
+
+// lexemes splits text into words, keeping the :* prefix marker of words
+// written with a trailing *.
+func lexemes(text string) []string {
+	words := make([]string, 0)
+	for _, field := range strings.Fields(text) {
+		prefix := strings.HasSuffix(field, "*")
+		parts := strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for j, part := range parts {
+			word := strings.ToLower(part)
+			if prefix && j == len(parts)-1 {
+				word += ":*"
+			}
+			words = append(words, word)
+		}
+	}
+	return words
+}
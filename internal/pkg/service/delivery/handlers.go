@@ -1,9 +1,12 @@
 package delivery
 
 import (
-	"forum/internal/models"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/pkg/response"
 	"forum/internal/pkg/service"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -23,25 +26,32 @@ func (sd *ServiceDelivery) Routing(r *mux.Router) {
 	r.HandleFunc("/service/clear", sd.ClearServiceHandler).Methods(http.MethodPost, http.MethodOptions)
 }
 
+// GetServiceStatusHandler answers from the maintained counters; ?exact=true
+// counts the rows instead.
 func (sd *ServiceDelivery) GetServiceStatusHandler(w http.ResponseWriter, r *http.Request) {
-	status, err := sd.serviceUsecase.GetServiceStatus()
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(status))
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+	exact, _ := strconv.ParseBool(r.URL.Query().Get("exact"))
+	status, err := sd.serviceUsecase.GetServiceStatus(r.Context(), exact)
+	if err != nil {
+		response.Error(w, r, err)
+		return
 	}
+	response.JSON(w, http.StatusOK, status)
 }
 
+// ClearServiceHandler clears everything, or one forum given ?forum=. When
+// a confirmation is due it answers 202 with the token to send as ?confirm=.
 func (sd *ServiceDelivery) ClearServiceHandler(w http.ResponseWriter, r *http.Request) {
-	err := sd.serviceUsecase.ClearService()
-	switch err {
-	case nil:
+	query := r.URL.Query()
+	forum := query.Get("forum")
+	confirmation, err := sd.serviceUsecase.ClearService(r.Context(), forum, query.Get("confirm"))
+	switch {
+	case err == nil && confirmation != nil:
+		response.JSON(w, http.StatusAccepted, confirmation)
+	case err == nil:
 		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", forum))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
@@ -1,8 +1,17 @@
 package service
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+	"time"
+)
 
 type ServiceRepository interface {
-	SelectServiceStatus() (*models.Service, error)
-	ClearService() error
+	SelectServiceStatus(ctx context.Context) (*models.Service, error)
+	SelectServiceCounters(ctx context.Context) (*models.Service, error)
+	ReconcileCounters(ctx context.Context) (*models.Service, *models.Service, error)
+	ClearService(ctx context.Context) error
+	ClearForum(ctx context.Context, forum string) error
+	InsertConfirmation(ctx context.Context, nonce string, expires time.Time) error
+	ConsumeConfirmation(ctx context.Context, nonce string) (bool, error)
 }
@@ -1,45 +1,249 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
 	"forum/internal/pkg/service"
-	"log"
+	"time"
 )
 
 type ServiceRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewServiceRepository(db *sql.DB) service.ServiceRepository {
+func NewServiceRepository(db *sql.DB, lg *logger.Logger) service.ServiceRepository {
 	return &ServiceRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (sr *ServiceRepository) SelectServiceStatus() (*models.Service, error) {
+// rowQueryer is what *sql.DB and *sql.Tx have in common for single rows.
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SelectServiceStatus counts every row, which takes longer the larger the
+// database grows.
+func (sr *ServiceRepository) SelectServiceStatus(ctx context.Context) (*models.Service, error) {
+	defer metrics.ObserveQuery("service", "SelectServiceStatus", time.Now())
+
+	srvc, err := countRows(ctx, sr.db)
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	return srvc, nil
+}
+
+func countRows(ctx context.Context, q rowQueryer) (*models.Service, error) {
 	srvc := &models.Service{}
-	row := sr.db.QueryRow(
+	row := q.QueryRowContext(
+		ctx,
 		`SELECT *
 		 FROM 	(SELECT COUNT(nickname) FROM users) as user_count,
 		 		(SELECT COUNT(slug) FROM forum) as forum_count,
 				(SELECT COUNT(id) FROM threads) as thread_count,
 				(SELECT COUNT(id) FROM posts) as post_count`)
 	err := row.Scan(&srvc.User, &srvc.Forum, &srvc.Thread, &srvc.Post)
+	return srvc, err
+}
+
+// SelectServiceCounters sums the counter shards the triggers keep up to
+// date.
+func (sr *ServiceRepository) SelectServiceCounters(ctx context.Context) (*models.Service, error) {
+	defer metrics.ObserveQuery("service", "SelectServiceCounters", time.Now())
+
+	srvc := &models.Service{}
+	row := sr.db.QueryRowContext(
+		ctx,
+		`SELECT
+			COALESCE(SUM(value) FILTER (WHERE name = 'users'), 0),
+			COALESCE(SUM(value) FILTER (WHERE name = 'forum'), 0),
+			COALESCE(SUM(value) FILTER (WHERE name = 'threads'), 0),
+			COALESCE(SUM(value) FILTER (WHERE name = 'posts'), 0)
+		 FROM service_counters;`)
+	err := row.Scan(&srvc.User, &srvc.Forum, &srvc.Thread, &srvc.Post)
 	if err != nil {
-		sr.logger.Println(err.Error())
+		sr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 	return srvc, nil
 }
 
-func (sr *ServiceRepository) ClearService() error {
-	_, err := sr.db.Exec("TRUNCATE users, forum, threads, posts, forum_users, votes;")
+// ReconcileCounters overwrites the counters with real counts, kept in the
+// first shard, and returns the values before and after. The shards stay
+// locked meanwhile, so writes committing during the count are neither lost
+// nor counted twice.
+func (sr *ServiceRepository) ReconcileCounters(ctx context.Context) (*models.Service, *models.Service, error) {
+	defer metrics.ObserveQuery("service", "ReconcileCounters", time.Now())
+
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return nil, nil, myerr.InternalDbError
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT name, value FROM service_counters ORDER BY name, shard FOR UPDATE;")
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, nil, myerr.RollbackError
+		}
+		sr.logger.Error(ctx, err.Error())
+		return nil, nil, myerr.InternalDbError
+	}
+	before := &models.Service{}
+	counters := map[string]*int64{"users": &before.User, "forum": &before.Forum, "threads": &before.Thread, "posts": &before.Post}
+	for rows.Next() {
+		var name string
+		var value int64
+		err = rows.Scan(&name, &value)
+		if err != nil {
+			break
+		}
+		if counter, ok := counters[name]; ok {
+			*counter += value
+		}
+	}
+	rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, nil, myerr.RollbackError
+		}
+		sr.logger.Error(ctx, err.Error())
+		return nil, nil, myerr.InternalDbError
+	}
+
+	after, err := countRows(ctx, tx)
+	if err == nil {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE service_counters c SET
+				value = CASE WHEN c.shard = 0 THEN v.value ELSE 0 END
+			 FROM (VALUES ('users', $1::BIGINT), ('forum', $2::BIGINT), ('threads', $3::BIGINT), ('posts', $4::BIGINT)) AS v(name, value)
+			 WHERE c.name = v.name;`,
+			after.User, after.Forum, after.Thread, after.Post)
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return nil, nil, myerr.RollbackError
+		}
+		sr.logger.Error(ctx, err.Error())
+		return nil, nil, myerr.InternalDbError
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, myerr.CommitError
+	}
+	return before, after, nil
+}
+
+// InsertConfirmation stores the nonce of a clear confirmation until it
+// expires, dropping the expired ones on the way.
+func (sr *ServiceRepository) InsertConfirmation(ctx context.Context, nonce string, expires time.Time) error {
+	defer metrics.ObserveQuery("service", "InsertConfirmation", time.Now())
+
+	_, err := sr.db.ExecContext(
+		ctx,
+		`WITH expired AS (
+			DELETE FROM clear_confirmations WHERE expires <= NOW()
+		 )
+		 INSERT INTO clear_confirmations (nonce, expires) VALUES ($1, $2);`,
+		nonce, expires)
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	return nil
+}
+
+// ConsumeConfirmation deletes the nonce and reports whether it was there
+// and unexpired, so of concurrent uses of one nonce only one succeeds.
+func (sr *ServiceRepository) ConsumeConfirmation(ctx context.Context, nonce string) (bool, error) {
+	defer metrics.ObserveQuery("service", "ConsumeConfirmation", time.Now())
+
+	result, err := sr.db.ExecContext(ctx, "DELETE FROM clear_confirmations WHERE nonce = $1 AND expires > NOW();", nonce)
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return false, myerr.InternalDbError
+	}
+	consumed, err := result.RowsAffected()
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return false, myerr.InternalDbError
+	}
+	return consumed == 1, nil
+}
+
+func (sr *ServiceRepository) ClearService(ctx context.Context) error {
+	defer metrics.ObserveQuery("service", "ClearService", time.Now())
+
+	_, err := sr.db.ExecContext(ctx, "TRUNCATE users, forum, threads, posts, forum_users, votes, post_revisions, thread_events, webhooks, webhook_outbox, webhook_deliveries, thread_subscriptions, notifications, post_mentions, api_tokens, forum_moderators, forum_bans, reports, forum_filters, held_content;")
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	return nil
+}
+
+// ClearForum removes the forum's threads with their posts and votes and
+// empties its users, keeping the forum itself and its settings.
+func (sr *ServiceRepository) ClearForum(ctx context.Context, forum string) error {
+	defer metrics.ObserveQuery("service", "ClearForum", time.Now())
+
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		sr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1 FOR UPDATE;", forum)
+	err = row.Scan(&forum)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return myerr.RollbackError
+		}
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			sr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+
+	for _, query := range []string{
+		"DELETE FROM votes WHERE thread IN (SELECT id FROM threads WHERE forum = $1);",
+		"DELETE FROM posts WHERE forum = $1;",
+		"DELETE FROM threads WHERE forum = $1;",
+		"DELETE FROM forum_users WHERE forum = $1;",
+		"UPDATE forum SET posts = 0, threads = 0 WHERE slug = $1;",
+	} {
+		_, err = tx.ExecContext(ctx, query, forum)
+		if err != nil {
+			rollbackError := tx.Rollback()
+			if rollbackError != nil {
+				return myerr.RollbackError
+			}
+			sr.logger.Error(ctx, err.Error())
+			return myerr.InternalDbError
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		sr.logger.Panicln(err.Error())
+		return myerr.CommitError
 	}
 	return nil
 }
@@ -1,8 +1,12 @@
 package service
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type ServiceUsecase interface {
-	GetServiceStatus() (*models.Service, error)
-	ClearService() error
+	GetServiceStatus(ctx context.Context, exact bool) (*models.Service, error)
+	ReconcileCounters(ctx context.Context) (*models.Service, *models.Service, error)
+	ClearService(ctx context.Context, forum string, confirm string) (*models.ClearConfirmation, error)
 }
@@ -1,26 +1,129 @@
 package usecase
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"forum/internal/config"
+	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/cursor"
 	"forum/internal/pkg/service"
+	"strings"
+	"time"
+)
+
+const (
+	// confirmationTTL is how long a clear confirmation stays valid.
+	confirmationTTL = time.Minute
+	nonceBytes      = 16
 )
 
 type ServiceUsecase struct {
-	repo service.ServiceRepository
+	repo          service.ServiceRepository
+	clearMode     string
+	confirmations *cursor.Codec
 }
 
-func NewServiceUsecase(repo service.ServiceRepository) service.ServiceUsecase {
+// NewServiceUsecase clears as clearMode allows; confirmations signs the
+// tokens admin mode asks for.
+func NewServiceUsecase(repo service.ServiceRepository, clearMode string, confirmations *cursor.Codec) service.ServiceUsecase {
 	return &ServiceUsecase{
-		repo: repo,
+		repo:          repo,
+		clearMode:     clearMode,
+		confirmations: confirmations,
 	}
 }
 
-func (su *ServiceUsecase) GetServiceStatus() (*models.Service, error) {
-	srvc, err := su.repo.SelectServiceStatus()
+// GetServiceStatus reads the maintained counters unless exact asks for
+// counting the rows.
+func (su *ServiceUsecase) GetServiceStatus(ctx context.Context, exact bool) (*models.Service, error) {
+	if exact {
+		return su.repo.SelectServiceStatus(ctx)
+	}
+	srvc, err := su.repo.SelectServiceCounters(ctx)
 	return srvc, err
 }
 
-func (su *ServiceUsecase) ClearService() error {
-	err := su.repo.ClearService()
-	return err
+func (su *ServiceUsecase) ReconcileCounters(ctx context.Context) (*models.Service, *models.Service, error) {
+	return su.repo.ReconcileCounters(ctx)
+}
+
+// clearKey is the signed content of a confirmation token. Purpose keeps
+// other tokens of the codec from passing for one and Nonce, stored until
+// the token is used, keeps it from passing twice.
+type clearKey struct {
+	Purpose string `json:"p"`
+	Forum   string `json:"f"`
+	Expires int64  `json:"e"`
+	Nonce   string `json:"n"`
+}
+
+const clearPurpose = "clear"
+
+// ClearService empties the database, or only the threads, posts, votes and
+// users of forum when it is set. In admin mode a call without confirm
+// clears nothing and returns the confirmation to repeat the call with. A
+// confirmation is spent by its first use, even one that fails to clear.
+func (su *ServiceUsecase) ClearService(ctx context.Context, forum string, confirm string) (*models.ClearConfirmation, error) {
+	switch su.clearMode {
+	case config.ClearTest:
+	case config.ClearAdmin:
+		if !auth.IsAdmin(ctx) {
+			return nil, myerr.Forbidden
+		}
+		if confirm == "" {
+			return su.confirmation(ctx, forum)
+		}
+		confirmed, err := su.confirmed(ctx, forum, confirm)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, myerr.InvalidConfirmation
+		}
+	default:
+		return nil, myerr.ClearDisabled
+	}
+
+	if forum != "" {
+		return nil, su.repo.ClearForum(ctx, forum)
+	}
+	return nil, su.repo.ClearService(ctx)
+}
+
+func (su *ServiceUsecase) confirmation(ctx context.Context, forum string) (*models.ClearConfirmation, error) {
+	buf := make([]byte, nonceBytes)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(buf)
+
+	expires := time.Now().Add(confirmationTTL)
+	err = su.repo.InsertConfirmation(ctx, nonce, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ClearConfirmation{
+		Forum:   forum,
+		Token:   su.confirmations.Encode(&clearKey{Purpose: clearPurpose, Forum: forum, Expires: expires.Unix(), Nonce: nonce}),
+		Expires: expires.Format(time.RFC3339),
+	}, nil
+}
+
+// confirmed checks the signed content before spending the nonce, so a
+// token for another forum does not burn the confirmation.
+func (su *ServiceUsecase) confirmed(ctx context.Context, forum string, confirm string) (bool, error) {
+	key := &clearKey{}
+	err := su.confirmations.Decode(confirm, key)
+	if err != nil ||
+		key.Purpose != clearPurpose ||
+		key.Nonce == "" ||
+		!strings.EqualFold(key.Forum, forum) ||
+		time.Now().Unix() > key.Expires {
+		return false, nil
+	}
+	return su.repo.ConsumeConfirmation(ctx, key.Nonce)
 }
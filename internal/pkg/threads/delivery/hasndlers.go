@@ -2,9 +2,11 @@ package delivery
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/cursor"
+	"forum/internal/pkg/response"
 	"forum/internal/pkg/threads"
 	"io/ioutil"
 	"net/http"
@@ -15,11 +17,13 @@ import (
 
 type ThreadDelivery struct {
 	threadUsecase threads.ThreadUsecase
+	cursors       *cursor.Codec
 }
 
-func NewForumDelivery(threadUsecase threads.ThreadUsecase) *ThreadDelivery {
+func NewForumDelivery(threadUsecase threads.ThreadUsecase, cursors *cursor.Codec) *ThreadDelivery {
 	return &ThreadDelivery{
 		threadUsecase: threadUsecase,
+		cursors:       cursors,
 	}
 }
 
@@ -28,6 +32,7 @@ func (td *ThreadDelivery) Routing(r *mux.Router) {
 	r.HandleFunc("/forum/{slug}/threads", td.GetThreadsHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/thread/{slug_or_id}/details", td.GetThreadHandler).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/thread/{slug_or_id}/details", td.UpdateThreadHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/thread/{slug_or_id}", td.DeleteThreadHandler).Methods(http.MethodDelete, http.MethodOptions)
 }
 
 func (td *ThreadDelivery) CreateThreadHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,53 +41,59 @@ func (td *ThreadDelivery) CreateThreadHandler(w http.ResponseWriter, r *http.Req
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, thredInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
-	thread, err := td.threadUsecase.CreateThread(thredInput.ToThread(slug))
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusCreated)
-		w.Write(models.ToBytes(thread))
-	case myerr.AuthorNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("user %s not found", thredInput.Author)}))
-	case myerr.ForumNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("forum %s not found", thredInput.Forum)}))
-	case myerr.ThreadAlreadyExist:
-		w.WriteHeader(http.StatusConflict)
-		w.Write(models.ToBytes(thread))
+	thread, err := td.threadUsecase.CreateThread(r.Context(), thredInput.ToThread(slug))
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, thread)
+	case errors.Is(err, myerr.AuthorNotExist):
+		response.Error(w, r, myerr.AuthorNotExist.WithMessage("user %s not found", thredInput.Author))
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("forum %s not found", slug))
+	case errors.Is(err, myerr.ThreadAlreadyExist):
+		response.JSON(w, http.StatusConflict, thread)
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
 func (td *ThreadDelivery) GetThreadsHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	tv := models.NewThreadsVars(mux.Vars(r), query)
+	if token := query.Get("cursor"); token != "" {
+		tv.After = &models.ThreadsCursor{}
+		err := td.cursors.Decode(token, tv.After)
+		if err != nil || tv.After.Desc != (tv.Sorting == "DESC") {
+			response.Error(w, r, myerr.InvalidCursor)
+			return
+		}
+	}
 
-	threads, err := td.threadUsecase.GetThreadsByForum(tv)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(threads))
-	case myerr.NoRows:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("forum %s not exist", tv.ForumSlug)}))
+	threads, err := td.threadUsecase.GetThreadsByForum(r.Context(), tv)
+	switch {
+	case err == nil:
+		if len(threads) != 0 && int64(len(threads)) == tv.Limit {
+			last := threads[len(threads)-1]
+			response.NextLink(w, r, td.cursors.Encode(&models.ThreadsCursor{
+				Created: last.Created,
+				Id:      last.Id,
+				Desc:    tv.Sorting == "DESC",
+			}))
+		}
+		response.JSON(w, http.StatusOK, threads)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("forum %s not exist", tv.ForumSlug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
@@ -90,17 +101,14 @@ func (td *ThreadDelivery) GetUsersHandler(w http.ResponseWriter, r *http.Request
 	query := r.URL.Query()
 	tv := models.NewThreadsVars(mux.Vars(r), query)
 
-	threads, err := td.threadUsecase.GetUsersByForum(tv)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(threads))
-	case myerr.NoRows:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("forum %s not exist", tv.ForumSlug)}))
+	threads, err := td.threadUsecase.GetUsersByForum(r.Context(), tv)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, threads)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("forum %s not exist", tv.ForumSlug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
@@ -113,17 +121,14 @@ func (td *ThreadDelivery) GetThreadHandler(w http.ResponseWriter, r *http.Reques
 		id = 0
 	}
 
-	thread, err := td.threadUsecase.GetThread(slug, id)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(thread))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("thread with {id: %d, slug: '%s'} not exist", id, slug)}))
+	thread, err := td.threadUsecase.GetThread(r.Context(), slug, id)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, thread)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
@@ -140,30 +145,45 @@ func (td *ThreadDelivery) UpdateThreadHandler(w http.ResponseWriter, r *http.Req
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, &thredUpdate)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	thredUpdate.Id = id
 	thredUpdate.Slug = slug
-	thread, err := td.threadUsecase.UpdateThread(thredUpdate)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(thread))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("thread with {id: %d, slug: '%s'} not exist", id, slug)}))
+	thread, err := td.threadUsecase.UpdateThread(r.Context(), thredUpdate)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, thread)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (td *ThreadDelivery) DeleteThreadHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug_or_id"]
+	id, err := strconv.ParseInt(slug, 10, 64)
+	if err == nil {
+		slug = ""
+	} else {
+		id = 0
+	}
+
+	err = td.threadUsecase.DeleteThread(r.Context(), slug, id)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread with {id: %d, slug: '%s'} not exist", id, slug))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
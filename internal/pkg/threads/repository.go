@@ -1,12 +1,16 @@
 package threads
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type ThreadRepository interface {
-	InsertThread(thread *models.Thread) error
-	SelectThreadBySlug(slug string) (*models.Thread, error)
-	SelectThreadsByForum(tv *models.ThreadsVars) ([]*models.Thread, error)
-	SelectUsersByForum(tv *models.ThreadsVars) ([]*models.Thread, error)
-	SelectThread(slug string, id int64) (*models.Thread, error)
-	UpdateThread(threadUpdate *models.ThreadUpdate) (*models.Thread, error)
+	InsertThread(ctx context.Context, thread *models.Thread) error
+	SelectThreadBySlug(ctx context.Context, slug string) (*models.Thread, error)
+	SelectThreadsByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error)
+	SelectUsersByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error)
+	SelectThread(ctx context.Context, slug string, id int64) (*models.Thread, error)
+	UpdateThread(ctx context.Context, threadUpdate *models.ThreadUpdate) (*models.Thread, error)
+	DeleteThread(ctx context.Context, slug string, id int64) error
 }
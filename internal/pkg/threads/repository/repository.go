@@ -6,70 +6,56 @@ import (
 	"fmt"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
 	"forum/internal/pkg/threads"
-	"log"
-	"regexp"
 	"time"
 )
 
 type ThreadRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewThreadRepository(db *sql.DB) threads.ThreadRepository {
+func NewThreadRepository(db *sql.DB, lg *logger.Logger) threads.ThreadRepository {
 	return &ThreadRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (tr *ThreadRepository) InsertThread(thread *models.Thread) error {
-	tx, err := tr.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (tr *ThreadRepository) InsertThread(ctx context.Context, thread *models.Thread) error {
+	defer metrics.ObserveQuery("threads", "InsertThread", time.Now())
+
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
-	row := tx.QueryRow(
+	row := tx.QueryRowContext(
+		ctx,
 		`INSERT INTO threads (title, message, slug, author, forum, created) 
 		 VALUES ($1, $2, $3,
 			COALESCE((SELECT nickname FROM users WHERE nickname = $4), $4),
 			COALESCE((SELECT slug FROM forum WHERE slug = $5), $5),
 			$6
 		 )
-		 RETURNING id, title, author, forum, message, votes, slug, created;`,
+		 RETURNING id, title, author, forum, message, votes, slug, created, status, pinned;`,
 		thread.Title, thread.Message, thread.Slug, thread.Author, thread.Forum, thread.Created,
 	)
 
-	err = row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+	err = row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
 		rollbackError := tx.Rollback()
 		if rollbackError != nil {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*threads_pkey.*", []byte(err.Error()))
-		if res {
-			return myerr.ThreadAlreadyExist
-		}
-
-		res, _ = regexp.Match(".*index_threads_slug.*", []byte(err.Error()))
-		if res {
-			return myerr.ThreadAlreadyExist
-		}
-
-		res, _ = regexp.Match(".*threads_author_fkey.*", []byte(err.Error()))
-		if res {
-			return myerr.AuthorNotExist
-		}
-
-		res, _ = regexp.Match(".*threads_forum_fkey.*", []byte(err.Error()))
-		if res {
-			return myerr.ForumNotExist
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-
-		tr.logger.Printf(err.Error())
-		return myerr.InternalDbError
+		return dbErr
 	}
 
 	err = tx.Commit()
@@ -79,56 +65,73 @@ func (tr *ThreadRepository) InsertThread(thread *models.Thread) error {
 	return nil
 }
 
-func (tr *ThreadRepository) SelectThreadBySlug(slug string) (*models.Thread, error) {
+func (tr *ThreadRepository) SelectThreadBySlug(ctx context.Context, slug string) (*models.Thread, error) {
+	defer metrics.ObserveQuery("threads", "SelectThreadBySlug", time.Now())
+
 	thread := &models.Thread{}
-	row := tr.db.QueryRow(
-		"SELECT id, title, author, forum, message, votes, slug, created FROM threads WHERE slug = $1",
+	row := tr.db.QueryRowContext(
+		ctx,
+		"SELECT id, title, author, forum, message, votes, slug, created, status, pinned FROM threads WHERE slug = $1",
 		slug,
 	)
-	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.NoRows
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return thread, nil
 }
 
-func (tr *ThreadRepository) SelectThreadsByForum(tv *models.ThreadsVars) ([]*models.Thread, error) {
-	row := tr.db.QueryRow(
+func (tr *ThreadRepository) SelectThreadsByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error) {
+	defer metrics.ObserveQuery("threads", "SelectThreadsByForum", time.Now())
+
+	row := tr.db.QueryRowContext(
+		ctx,
 		"SELECT slug FROM forum WHERE slug = $1",
 		tv.ForumSlug,
 	)
 	buf := ""
 	err := row.Scan(&buf)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.NoRows
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-		tr.logger.Println(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 
 	queryStr := `
-					SELECT id, title, author, forum, message, votes, slug, created
+					SELECT id, title, author, forum, message, votes, slug, created, status, pinned
 					FROM threads
-					WHERE forum = $1 %s
-					ORDER BY created %s
+					WHERE forum = $1 AND status <> 'hidden' %s %s
+					ORDER BY created %s, id %s
 					LIMIT $2;
 				`
+	pinned := ""
+	if tv.Pinned {
+		pinned = "AND pinned"
+	}
 	var rows *sql.Rows
-	if tv.Since != "" {
-		queryStr = fmt.Sprintf(queryStr, fmt.Sprintf(`AND created %s $3::timestamp with time zone`, tv.Sign), tv.Sorting)
-		rows, err = tr.db.Query(queryStr, tv.ForumSlug, tv.Limit, tv.Since)
-	} else {
-		queryStr = fmt.Sprintf(queryStr, "", tv.Sorting)
-		rows, err = tr.db.Query(queryStr, tv.ForumSlug, tv.Limit)
+	switch {
+	case tv.After != nil:
+		sign := ">"
+		if tv.Sorting == "DESC" {
+			sign = "<"
+		}
+		queryStr = fmt.Sprintf(queryStr, pinned, fmt.Sprintf(`AND (created, id) %s ($3::timestamp with time zone, $4)`, sign), tv.Sorting, tv.Sorting)
+		rows, err = tr.db.QueryContext(ctx, queryStr, tv.ForumSlug, tv.Limit, tv.After.Created, tv.After.Id)
+	case tv.Since != "":
+		queryStr = fmt.Sprintf(queryStr, pinned, fmt.Sprintf(`AND created %s $3::timestamp with time zone`, tv.Sign), tv.Sorting, tv.Sorting)
+		rows, err = tr.db.QueryContext(ctx, queryStr, tv.ForumSlug, tv.Limit, tv.Since)
+	default:
+		queryStr = fmt.Sprintf(queryStr, pinned, "", tv.Sorting, tv.Sorting)
+		rows, err = tr.db.QueryContext(ctx, queryStr, tv.ForumSlug, tv.Limit)
 	}
 	if err != nil {
-		tr.logger.Println(err.Error())
+		tr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
@@ -138,9 +141,9 @@ func (tr *ThreadRepository) SelectThreadsByForum(tv *models.ThreadsVars) ([]*mod
 		t := &time.Time{}
 		err = rows.Scan(
 			&thread.Id, &thread.Title, &thread.Author, &thread.Forum,
-			&thread.Message, &thread.Votes, &thread.Slug, &t)
+			&thread.Message, &thread.Votes, &thread.Slug, &t, &thread.Status, &thread.Pinned)
 		if err != nil {
-			tr.logger.Println(err.Error())
+			tr.logger.Error(ctx, err.Error())
 			return nil, myerr.InternalDbError
 		}
 
@@ -151,72 +154,79 @@ func (tr *ThreadRepository) SelectThreadsByForum(tv *models.ThreadsVars) ([]*mod
 	return threads, nil
 }
 
-func (tr *ThreadRepository) SelectUsersByForum(tv *models.ThreadsVars) ([]*models.Thread, error) {
-	row := tr.db.QueryRow(
+func (tr *ThreadRepository) SelectUsersByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error) {
+	defer metrics.ObserveQuery("threads", "SelectUsersByForum", time.Now())
+
+	row := tr.db.QueryRowContext(
+		ctx,
 		"SELECT slug FROM forum WHERE slug = $1",
 		tv.ForumSlug,
 	)
 	buf := ""
 	err := row.Scan(&buf)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.NoRows
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-		tr.logger.Println(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 
 	return nil, nil
 }
 
-func (tr *ThreadRepository) SelectThread(slug string, id int64) (*models.Thread, error) {
+func (tr *ThreadRepository) SelectThread(ctx context.Context, slug string, id int64) (*models.Thread, error) {
+	defer metrics.ObserveQuery("threads", "SelectThread", time.Now())
+
 	thread := &models.Thread{}
-	row := tr.db.QueryRow(
-		"SELECT id, title, author, forum, message, votes, slug, created from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1",
+	row := tr.db.QueryRowContext(
+		ctx,
+		"SELECT id, title, author, forum, message, votes, slug, created, status, pinned from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1",
 		id, slug,
 	)
-	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-		tr.logger.Println(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 	return thread, nil
 }
 
-func (tr *ThreadRepository) UpdateThread(threadUpdate *models.ThreadUpdate) (*models.Thread, error) {
-	tx, err := tr.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (tr *ThreadRepository) UpdateThread(ctx context.Context, threadUpdate *models.ThreadUpdate) (*models.Thread, error) {
+	defer metrics.ObserveQuery("threads", "UpdateThread", time.Now())
+
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, myerr.InternalDbError
 	}
 
 	thread := &models.Thread{}
-	row := tx.QueryRow(
+	row := tx.QueryRowContext(
+		ctx,
 		`UPDATE threads SET 
 			title = CASE WHEN $1 = '' THEN title ELSE $1 END, 
-			message = CASE WHEN $2 = '' THEN message ELSE $2 END 
+			message = CASE WHEN $2 = '' THEN message ELSE $2 END,
+			status = CASE WHEN $5 = '' THEN status ELSE $5 END,
+			pinned = COALESCE($6::BOOLEAN, pinned)
 		 WHERE id = (SELECT id from threads WHERE 0 = $3 AND slug = $4 OR $4 = '' AND id = $3)
-		 RETURNING id, title, author, forum, message, votes, slug, created;`,
-		threadUpdate.Title, threadUpdate.Message, threadUpdate.Id, threadUpdate.Slug)
+		 RETURNING id, title, author, forum, message, votes, slug, created, status, pinned;`,
+		threadUpdate.Title, threadUpdate.Message, threadUpdate.Id, threadUpdate.Slug, threadUpdate.Status, threadUpdate.Pinned)
 
-	err = row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+	err = row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
 		rollbackError := tx.Rollback()
 		if rollbackError != nil {
 			return nil, myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
 		}
-
-		tr.logger.Printf(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 
 	err = tx.Commit()
@@ -225,3 +235,58 @@ func (tr *ThreadRepository) UpdateThread(threadUpdate *models.ThreadUpdate) (*mo
 	}
 	return thread, nil
 }
+
+// DeleteThread removes the thread with its votes and posts in one
+// transaction. Triggers keep the forum counters in step.
+func (tr *ThreadRepository) DeleteThread(ctx context.Context, slug string, id int64) error {
+	defer metrics.ObserveQuery("threads", "DeleteThread", time.Now())
+
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return myerr.InternalDbError
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT id FROM threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1 FOR UPDATE",
+		id, slug)
+	err = row.Scan(&id)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return myerr.RollbackError
+		}
+
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+
+	for _, query := range []string{
+		"DELETE FROM votes WHERE thread = $1;",
+		"DELETE FROM posts WHERE thread = $1;",
+		"DELETE FROM threads WHERE id = $1;",
+	} {
+		_, err = tx.ExecContext(ctx, query, id)
+		if err != nil {
+			rollbackError := tx.Rollback()
+			if rollbackError != nil {
+				return myerr.RollbackError
+			}
+
+			dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+			if !known {
+				tr.logger.Error(ctx, err.Error())
+			}
+			return dbErr
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return myerr.CommitError
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package threads
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type ThreadUsecase interface {
-	CreateThread(thread *models.Thread) (*models.Thread, error)
-	GetThreadsByForum(tv *models.ThreadsVars) ([]*models.Thread, error)
-	GetUsersByForum(tv *models.ThreadsVars) ([]*models.Thread, error)
-	GetThread(slug string, id int64) (*models.Thread, error)
-	UpdateThread(thredUpdate *models.ThreadUpdate) (*models.Thread, error)
+	CreateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error)
+	GetThreadsByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error)
+	GetUsersByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error)
+	GetThread(ctx context.Context, slug string, id int64) (*models.Thread, error)
+	UpdateThread(ctx context.Context, thredUpdate *models.ThreadUpdate) (*models.Thread, error)
+	DeleteThread(ctx context.Context, slug string, id int64) error
 }
@@ -1,23 +1,57 @@
 package usecase
 
 import (
+	"context"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/bans"
+	"forum/internal/pkg/filters"
 	"forum/internal/pkg/threads"
 )
 
 type ThreadUsecase struct {
-	repo threads.ThreadRepository
+	repo    threads.ThreadRepository
+	roles   auth.RoleResolver
+	bans    bans.BanChecker
+	filters filters.Screener
 }
 
-func NewThreadUsecase(repo threads.ThreadRepository) threads.ThreadUsecase {
+func NewThreadUsecase(repo threads.ThreadRepository, roles auth.RoleResolver, bans bans.BanChecker, filters filters.Screener) threads.ThreadUsecase {
 	return &ThreadUsecase{
-		repo: repo,
+		repo:    repo,
+		roles:   roles,
+		bans:    bans,
+		filters: filters,
 	}
 }
 
-func (tu *ThreadUsecase) CreateThread(thread *models.Thread) (*models.Thread, error) {
-	err := tu.repo.InsertThread(thread)
+func (tu *ThreadUsecase) CreateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error) {
+	if thread.Author == "" {
+		thread.Author = auth.Nickname(ctx)
+	}
+	err := auth.Authorize(ctx, thread.Author)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tu.bans.CheckAllowed(ctx, thread.Forum, thread.Author)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tu.filters.Screen(ctx, &filters.Content{
+		Kind:    models.HeldThread,
+		Forum:   thread.Forum,
+		Texts:   []*string{&thread.Title, &thread.Message},
+		Authors: []string{thread.Author, thread.Author},
+		Payload: thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tu.repo.InsertThread(ctx, thread)
 	switch err {
 	case nil:
 		return thread, nil
@@ -26,7 +60,7 @@ func (tu *ThreadUsecase) CreateThread(thread *models.Thread) (*models.Thread, er
 	case myerr.ForumNotExist:
 		return nil, myerr.ForumNotExist
 	case myerr.ThreadAlreadyExist:
-		thread, err = tu.repo.SelectThreadBySlug(thread.Slug)
+		thread, err = tu.repo.SelectThreadBySlug(ctx, thread.Slug)
 		if err != nil {
 			return nil, err
 		}
@@ -36,22 +70,74 @@ func (tu *ThreadUsecase) CreateThread(thread *models.Thread) (*models.Thread, er
 	}
 }
 
-func (tu *ThreadUsecase) GetThreadsByForum(tv *models.ThreadsVars) ([]*models.Thread, error) {
-	threads, err := tu.repo.SelectThreadsByForum(tv)
+func (tu *ThreadUsecase) GetThreadsByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error) {
+	threads, err := tu.repo.SelectThreadsByForum(ctx, tv)
 	return threads, err
 }
 
-func (tu *ThreadUsecase) GetUsersByForum(tv *models.ThreadsVars) ([]*models.Thread, error) {
-	threads, err := tu.repo.SelectUsersByForum(tv)
+func (tu *ThreadUsecase) GetUsersByForum(ctx context.Context, tv *models.ThreadsVars) ([]*models.Thread, error) {
+	threads, err := tu.repo.SelectUsersByForum(ctx, tv)
 	return threads, err
 }
 
-func (tu *ThreadUsecase) GetThread(slug string, id int64) (*models.Thread, error) {
-	thread, err := tu.repo.SelectThread(slug, id)
-	return thread, err
+// GetThread shows hidden threads to the moderators of their forum only, so
+// they can restore them.
+func (tu *ThreadUsecase) GetThread(ctx context.Context, slug string, id int64) (*models.Thread, error) {
+	thread, err := tu.repo.SelectThread(ctx, slug, id)
+	if err != nil {
+		return nil, err
+	}
+	if thread.Status == models.ThreadHidden && auth.AuthorizeModerator(ctx, tu.roles, thread.Forum) != nil {
+		return nil, myerr.ThreadNotExists
+	}
+	return thread, nil
 }
 
-func (tu *ThreadUsecase) UpdateThread(threadUpdate *models.ThreadUpdate) (*models.Thread, error) {
-	thread, err := tu.repo.UpdateThread(threadUpdate)
+// UpdateThread lets the author and the moderators edit the text of a
+// thread while closing and pinning it is up to the moderators.
+func (tu *ThreadUsecase) UpdateThread(ctx context.Context, threadUpdate *models.ThreadUpdate) (*models.Thread, error) {
+	if threadUpdate.Status != "" && !models.ValidThreadStatus(threadUpdate.Status) {
+		return nil, myerr.InvalidThreadStatus
+	}
+	err := tu.authorizeUpdate(ctx, threadUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := tu.repo.UpdateThread(ctx, threadUpdate)
 	return thread, err
 }
+
+func (tu *ThreadUsecase) authorizeUpdate(ctx context.Context, threadUpdate *models.ThreadUpdate) error {
+	if auth.IsAdmin(ctx) {
+		return nil
+	}
+	if auth.Nickname(ctx) == "" {
+		return myerr.Unauthorized
+	}
+
+	thread, err := tu.repo.SelectThread(ctx, threadUpdate.Slug, threadUpdate.Id)
+	if err != nil {
+		return err
+	}
+	if threadUpdate.Status != "" || threadUpdate.Pinned != nil {
+		return auth.AuthorizeModerator(ctx, tu.roles, thread.Forum)
+	}
+	return auth.AuthorizeAuthor(ctx, tu.roles, thread.Forum, thread.Author)
+}
+
+// DeleteThread removes a thread together with its posts and votes and is
+// reserved to admins and the moderators of its forum.
+func (tu *ThreadUsecase) DeleteThread(ctx context.Context, slug string, id int64) error {
+	if !auth.IsAdmin(ctx) {
+		thread, err := tu.repo.SelectThread(ctx, slug, id)
+		if err != nil {
+			return err
+		}
+		err = auth.AuthorizeModerator(ctx, tu.roles, thread.Forum)
+		if err != nil {
+			return err
+		}
+	}
+	return tu.repo.DeleteThread(ctx, slug, id)
+}
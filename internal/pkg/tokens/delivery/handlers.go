@@ -0,0 +1,84 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/response"
+	"forum/internal/pkg/tokens"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type TokenDelivery struct {
+	tokenUsecase tokens.TokenUsecase
+}
+
+func NewTokenDelivery(tokenUsecase tokens.TokenUsecase) *TokenDelivery {
+	return &TokenDelivery{
+		tokenUsecase: tokenUsecase,
+	}
+}
+
+func (td *TokenDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/user/{nickname}/tokens", td.IssueTokenHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/tokens", td.GetTokensHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/user/{nickname}/tokens/{id:[0-9]+}", td.RevokeTokenHandler).Methods(http.MethodDelete, http.MethodOptions)
+}
+
+func (td *TokenDelivery) IssueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	input := &models.ApiTokenInput{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	if len(buf) != 0 {
+		err = json.Unmarshal(buf, input)
+		if err != nil {
+			response.Error(w, r, myerr.InvalidBody)
+			return
+		}
+	}
+
+	token, err := td.tokenUsecase.IssueToken(r.Context(), nickname, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, token)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (td *TokenDelivery) GetTokensHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	tokens, err := td.tokenUsecase.GetTokens(r.Context(), nickname)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, tokens)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (td *TokenDelivery) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := mux.Vars(r)["nickname"]
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	err := td.tokenUsecase.RevokeToken(r.Context(), nickname, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,13 @@
+package tokens
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type TokenRepository interface {
+	InsertToken(ctx context.Context, nickname string, name string, hash []byte) (*models.ApiToken, error)
+	SelectTokens(ctx context.Context, nickname string) ([]*models.ApiToken, error)
+	DeleteToken(ctx context.Context, nickname string, id int64) error
+	SelectNickname(ctx context.Context, hash []byte) (string, error)
+}
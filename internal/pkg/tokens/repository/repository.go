@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/tokens"
+	"time"
+)
+
+type TokenRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewTokenRepository(db *sql.DB, lg *logger.Logger) tokens.TokenRepository {
+	return &TokenRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+func (tr *TokenRepository) InsertToken(ctx context.Context, nickname string, name string, hash []byte) (*models.ApiToken, error) {
+	defer metrics.ObserveQuery("tokens", "InsertToken", time.Now())
+
+	token := &models.ApiToken{}
+	row := tr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_tokens (nickname, name, hash)
+		 VALUES (COALESCE((SELECT nickname FROM users WHERE nickname = $1), $1), $2, $3)
+		 RETURNING id, nickname, name, created;`,
+		nickname, name, hash)
+	err := row.Scan(&token.Id, &token.Nickname, &token.Name, &token.Created)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	return token, nil
+}
+
+func (tr *TokenRepository) SelectTokens(ctx context.Context, nickname string) ([]*models.ApiToken, error) {
+	defer metrics.ObserveQuery("tokens", "SelectTokens", time.Now())
+
+	row := tr.db.QueryRowContext(ctx, "SELECT nickname FROM users WHERE nickname = $1;", nickname)
+	err := row.Scan(&nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	rows, err := tr.db.QueryContext(
+		ctx,
+		"SELECT id, nickname, name, created FROM api_tokens WHERE nickname = $1 ORDER BY id;",
+		nickname)
+	if err != nil {
+		tr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	tokens := make([]*models.ApiToken, 0)
+	for rows.Next() {
+		token := &models.ApiToken{}
+		err = rows.Scan(&token.Id, &token.Nickname, &token.Name, &token.Created)
+		if err != nil {
+			tr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (tr *TokenRepository) DeleteToken(ctx context.Context, nickname string, id int64) error {
+	defer metrics.ObserveQuery("tokens", "DeleteToken", time.Now())
+
+	res, err := tr.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = $1 AND nickname = $2;", id, nickname)
+	if err != nil {
+		tr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		tr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	if deleted == 0 {
+		return myerr.TokenNotExist
+	}
+	return nil
+}
+
+func (tr *TokenRepository) SelectNickname(ctx context.Context, hash []byte) (string, error) {
+	defer metrics.ObserveQuery("tokens", "SelectNickname", time.Now())
+
+	var nickname string
+	row := tr.db.QueryRowContext(ctx, "SELECT nickname FROM api_tokens WHERE hash = $1;", hash)
+	err := row.Scan(&nickname)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.TokenNotExist)
+		if !known {
+			tr.logger.Error(ctx, err.Error())
+		}
+		return "", dbErr
+	}
+	return nickname, nil
+}
@@ -0,0 +1,23 @@
+package tokens
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+// FirstTokenName names the token handed out on sign-up.
+const FirstTokenName = "initial"
+
+type TokenUsecase interface {
+	IssueToken(ctx context.Context, nickname string, input *models.ApiTokenInput) (*models.ApiToken, error)
+	NewToken() (string, []byte, error)
+	GetTokens(ctx context.Context, nickname string) ([]*models.ApiToken, error)
+	RevokeToken(ctx context.Context, nickname string, id int64) error
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// Issuer mints the first token of a user being created, a secret and the
+// hash to store, so the user and the token are inserted together.
+type Issuer interface {
+	NewToken() (string, []byte, error)
+}
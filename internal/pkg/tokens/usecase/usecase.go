@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/tokens"
+	"strings"
+)
+
+const (
+	tokenPrefix = "forum_"
+	tokenBytes  = 32
+)
+
+type TokenUsecase struct {
+	repo tokens.TokenRepository
+}
+
+func NewTokenUsecase(repo tokens.TokenRepository) tokens.TokenUsecase {
+	return &TokenUsecase{
+		repo: repo,
+	}
+}
+
+// IssueToken creates a personal token. Only its hash is kept, so the token
+// is returned here and never again.
+func (tu *TokenUsecase) IssueToken(ctx context.Context, nickname string, input *models.ApiTokenInput) (*models.ApiToken, error) {
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, hash, err := tu.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	token, err := tu.repo.InsertToken(ctx, nickname, input.Name, hash)
+	if err != nil {
+		return nil, err
+	}
+	token.Token = secret
+	return token, nil
+}
+
+// NewToken returns a fresh secret and the hash it is stored and looked up
+// by.
+func (tu *TokenUsecase) NewToken() (string, []byte, error) {
+	buf := make([]byte, tokenBytes)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", nil, err
+	}
+	secret := tokenPrefix + hex.EncodeToString(buf)
+	return secret, hashToken(secret), nil
+}
+
+func (tu *TokenUsecase) GetTokens(ctx context.Context, nickname string) ([]*models.ApiToken, error) {
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	return tu.repo.SelectTokens(ctx, nickname)
+}
+
+func (tu *TokenUsecase) RevokeToken(ctx context.Context, nickname string, id int64) error {
+	err := auth.Authorize(ctx, nickname)
+	if err != nil {
+		return err
+	}
+	return tu.repo.DeleteToken(ctx, nickname, id)
+}
+
+// Authenticate answers Unauthorized for tokens it never issued or that were
+// revoked.
+func (tu *TokenUsecase) Authenticate(ctx context.Context, token string) (string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", myerr.Unauthorized
+	}
+	nickname, err := tu.repo.SelectNickname(ctx, hashToken(token))
+	if errors.Is(err, myerr.TokenNotExist) {
+		return "", myerr.Unauthorized
+	}
+	return nickname, err
+}
+
+// hashToken needs no salt: tokens are random and long enough that a plain
+// digest cannot be reversed.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -2,9 +2,10 @@ package delivery
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/response"
 	"forum/internal/pkg/user"
 	"io/ioutil"
 	"net/http"
@@ -34,47 +35,39 @@ func (ud *UserDelivery) CreateUserHandler(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, userInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
-	users, inserted, err := ud.userUsecase.CreateUser(userInput.ToUser(nickname))
+	users, inserted, err := ud.userUsecase.CreateUser(r.Context(), userInput.ToUser(nickname))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 		return
 	}
 
 	if inserted {
-		w.WriteHeader(http.StatusCreated)
-		w.Write(models.ToBytes(users[0]))
+		response.JSON(w, http.StatusCreated, users[0])
 	} else {
-		w.WriteHeader(http.StatusConflict)
-		w.Write(models.ToBytes(users))
+		response.JSON(w, http.StatusConflict, users)
 	}
 }
 
 func (ud *UserDelivery) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	nickname := mux.Vars(r)["nickname"]
-	user, err := ud.userUsecase.GetUser(nickname)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(user))
-	case myerr.NoRows:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("Can't find user with nickname %s", nickname)}))
+	user, err := ud.userUsecase.GetUser(r.Context(), nickname)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, user)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
 	default:
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
 
@@ -84,31 +77,25 @@ func (ud *UserDelivery) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, userInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
-	user, err := ud.userUsecase.UpdateUser(userInput.ToUser(nickname))
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(user))
-	case myerr.NoRows:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("Can't find user with nickname %s", nickname)}))
-	case myerr.EmailAlreadyExist:
-		w.WriteHeader(http.StatusConflict)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("Can't update email for user with nickname %s", nickname)}))
+	user, err := ud.userUsecase.UpdateUser(r.Context(), userInput.ToUser(nickname))
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, user)
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("Can't find user with nickname %s", nickname))
+	case errors.Is(err, myerr.EmailAlreadyExist):
+		response.Error(w, r, myerr.EmailAlreadyExist.WithMessage("Can't update email for user with nickname %s", nickname))
 	default:
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
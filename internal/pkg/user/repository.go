@@ -1,10 +1,13 @@
 package user
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type UserRepository interface {
-	InsertUser(user *models.User) error
-	UpdateUser(user *models.User) error
-	SelectUser(nickname string) (*models.User, error)
-	SelectUsersIfExists(nickname string, email string) ([]*models.User, error)
+	InsertUser(ctx context.Context, user *models.User, tokenHash []byte) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	SelectUser(ctx context.Context, nickname string) (*models.User, error)
+	SelectUsersIfExists(ctx context.Context, nickname string, email string) ([]*models.User, error)
 }
@@ -5,30 +5,37 @@ import (
 	"database/sql"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/tokens"
 	"forum/internal/pkg/user"
-	"log"
-	"regexp"
+	"time"
 )
 
 type UserRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewUserRepository(db *sql.DB) user.UserRepository {
+func NewUserRepository(db *sql.DB, lg *logger.Logger) user.UserRepository {
 	return &UserRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (ur *UserRepository) InsertUser(user *models.User) error {
-	tx, err := ur.db.BeginTx(context.Background(), &sql.TxOptions{})
+// InsertUser stores the first token of the user, when tokenHash is set, in
+// the same transaction, so no user is left without a way to get a token.
+func (ur *UserRepository) InsertUser(ctx context.Context, user *models.User, tokenHash []byte) error {
+	defer metrics.ObserveQuery("user", "InsertUser", time.Now())
+
+	tx, err := ur.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
-	row := tx.QueryRow(
+	row := tx.QueryRowContext(
+		ctx,
 		"INSERT INTO users (nickname, fullname, about, email) VALUES ($1, $2, $3, $4) RETURNING nickname, fullname, about, email;",
 		user.Nickname, user.Fullname, user.About, user.Email,
 	)
@@ -41,18 +48,26 @@ func (ur *UserRepository) InsertUser(user *models.User) error {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*users_pkey.*", []byte(err.Error()))
-		if res {
-			return myerr.NicknameAlreadyExist
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			ur.logger.Error(ctx, err.Error())
 		}
+		return dbErr
+	}
 
-		res, _ = regexp.Match(".*users_email_key.*", []byte(err.Error()))
-		if res {
-			return myerr.EmailAlreadyExist
+	if tokenHash != nil {
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO api_tokens (nickname, name, hash) VALUES ($1, $2, $3);",
+			user.Nickname, tokens.FirstTokenName, tokenHash)
+		if err != nil {
+			rollbackError := tx.Rollback()
+			if rollbackError != nil {
+				return myerr.RollbackError
+			}
+			ur.logger.Error(ctx, err.Error())
+			return myerr.InternalDbError
 		}
-
-		ur.logger.Printf(err.Error())
-		return myerr.InternalDbError
 	}
 
 	err = tx.Commit()
@@ -62,13 +77,16 @@ func (ur *UserRepository) InsertUser(user *models.User) error {
 	return nil
 }
 
-func (ur *UserRepository) UpdateUser(user *models.User) error {
-	tx, err := ur.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (ur *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	defer metrics.ObserveQuery("user", "UpdateUser", time.Now())
+
+	tx, err := ur.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
-	row := tx.QueryRow(
+	row := tx.QueryRowContext(
+		ctx,
 		"UPDATE users SET fullname = $2, about = $3, email = $4 WHERE nickname = $1 RETURNING nickname, fullname, about, email;",
 		user.Nickname, user.Fullname, user.About, user.Email,
 	)
@@ -80,18 +98,11 @@ func (ur *UserRepository) UpdateUser(user *models.User) error {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return myerr.NoRows
-		}
-
-		res, _ = regexp.Match(".*users_email_key.*", []byte(err.Error()))
-		if res {
-			return myerr.EmailAlreadyExist
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			ur.logger.Error(ctx, err.Error())
 		}
-
-		ur.logger.Printf(err.Error())
-		return myerr.InternalDbError
+		return dbErr
 	}
 
 	err = tx.Commit()
@@ -101,8 +112,11 @@ func (ur *UserRepository) UpdateUser(user *models.User) error {
 	return nil
 }
 
-func (ur *UserRepository) SelectUser(nickname string) (*models.User, error) {
-	row := ur.db.QueryRow(
+func (ur *UserRepository) SelectUser(ctx context.Context, nickname string) (*models.User, error) {
+	defer metrics.ObserveQuery("user", "SelectUser", time.Now())
+
+	row := ur.db.QueryRowContext(
+		ctx,
 		"SELECT nickname, fullname, about, email FROM users WHERE nickname = $1",
 		nickname,
 	)
@@ -110,27 +124,26 @@ func (ur *UserRepository) SelectUser(nickname string) (*models.User, error) {
 	user := &models.User{}
 	err := row.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return nil, myerr.NoRows
+		dbErr, known := myerr.FromDb(err, myerr.UserNotExist)
+		if !known {
+			ur.logger.Error(ctx, err.Error())
 		}
-	}
-
-	if err != nil {
-		ur.logger.Printf(err.Error())
-		return nil, myerr.InternalDbError
+		return nil, dbErr
 	}
 
 	return user, nil
 }
 
-func (ur *UserRepository) SelectUsersIfExists(nickname string, email string) ([]*models.User, error) {
-	rows, err := ur.db.Query(
+func (ur *UserRepository) SelectUsersIfExists(ctx context.Context, nickname string, email string) ([]*models.User, error) {
+	defer metrics.ObserveQuery("user", "SelectUsersIfExists", time.Now())
+
+	rows, err := ur.db.QueryContext(
+		ctx,
 		"SELECT nickname, fullname, about, email FROM users WHERE nickname = $1 OR email = $2;",
 		nickname, email,
 	)
 	if err != nil {
-		ur.logger.Printf(err.Error())
+		ur.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 	defer rows.Close()
@@ -140,7 +153,7 @@ func (ur *UserRepository) SelectUsersIfExists(nickname string, email string) ([]
 		user := &models.User{}
 		err = rows.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 		if err != nil {
-			ur.logger.Printf(err.Error())
+			ur.logger.Error(ctx, err.Error())
 			return nil, myerr.InternalDbError
 		}
 		users = append(users, user)
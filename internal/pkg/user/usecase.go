@@ -1,9 +1,12 @@
 package user
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type UserUsecase interface {
-	GetUser(nickname string) (*models.User, error)
-	CreateUser(user *models.User) ([]*models.User, bool, error)
-	UpdateUser(user *models.User) (*models.User, error)
+	GetUser(ctx context.Context, nickname string) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) ([]*models.User, bool, error)
+	UpdateUser(ctx context.Context, user *models.User) (*models.User, error)
 }
@@ -1,50 +1,73 @@
 package usecase
 
 import (
+	"context"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/tokens"
 	"forum/internal/pkg/user"
 	"log"
 )
 
 type UserUsecase struct {
 	repo   user.UserRepository
+	tokens tokens.Issuer
 	logger *log.Logger
 }
 
-func NewUserUsecase(repo user.UserRepository) user.UserUsecase {
+func NewUserUsecase(repo user.UserRepository, tokens tokens.Issuer) user.UserUsecase {
 	return &UserUsecase{
 		repo:   repo,
+		tokens: tokens,
 		logger: log.Default(),
 	}
 }
 
-func (uu *UserUsecase) GetUser(nickname string) (*models.User, error) {
-	user, err := uu.repo.SelectUser(nickname)
+func (uu *UserUsecase) GetUser(ctx context.Context, nickname string) (*models.User, error) {
+	user, err := uu.repo.SelectUser(ctx, nickname)
 	return user, err
 }
 
-func (uu *UserUsecase) CreateUser(user *models.User) ([]*models.User, bool, error) {
-	err := uu.repo.InsertUser(user)
+// CreateUser hands the new user their first token while ownership checks
+// are enforced, since every other way to get one needs a token already.
+func (uu *UserUsecase) CreateUser(ctx context.Context, user *models.User) ([]*models.User, bool, error) {
+	var secret string
+	var hash []byte
+	if auth.Enforced(ctx) {
+		var err error
+		secret, hash, err = uu.tokens.NewToken()
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	err := uu.repo.InsertUser(ctx, user, hash)
 	users := make([]*models.User, 0)
 
 	switch err {
 	case nil:
+		user.Token = secret
 		users = append(users, user)
 		return append(users, user), true, err
 	case myerr.NicknameAlreadyExist:
-		users, err = uu.repo.SelectUsersIfExists(user.Nickname, user.Email)
+		users, err = uu.repo.SelectUsersIfExists(ctx, user.Nickname, user.Email)
 		return users, false, err
 	case myerr.EmailAlreadyExist:
-		users, err = uu.repo.SelectUsersIfExists(user.Nickname, user.Email)
+		users, err = uu.repo.SelectUsersIfExists(ctx, user.Nickname, user.Email)
 		return users, false, err
 	default:
 		return nil, false, err
 	}
 }
 
-func (uu *UserUsecase) UpdateUser(user *models.User) (*models.User, error) {
-	userOld, err := uu.repo.SelectUser(user.Nickname)
+func (uu *UserUsecase) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	err := auth.Authorize(ctx, user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	userOld, err := uu.repo.SelectUser(ctx, user.Nickname)
 	if err == nil {
 		if user.Fullname == "" {
 			user.Fullname = userOld.Fullname
@@ -59,6 +82,6 @@ func (uu *UserUsecase) UpdateUser(user *models.User) (*models.User, error) {
 		}
 	}
 
-	err = uu.repo.UpdateUser(user)
+	err = uu.repo.UpdateUser(ctx, user)
 	return user, err
 }
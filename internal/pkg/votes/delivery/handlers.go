@@ -2,9 +2,10 @@ package delivery
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/response"
 	"forum/internal/pkg/votes"
 	"io/ioutil"
 	"net/http"
@@ -32,15 +33,13 @@ func (vd *VoteDelivery) UpdateVoteHandler(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 1"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
 	err = json.Unmarshal(buf, &vote)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(models.ToBytes(models.Error{Message: "invalid body 2"}))
+		response.Error(w, r, myerr.InvalidBody)
 		return
 	}
 
@@ -52,19 +51,15 @@ func (vd *VoteDelivery) UpdateVoteHandler(w http.ResponseWriter, r *http.Request
 		vote.ThreadSlug = ""
 	}
 
-	thread, err := vd.voteUsecase.UpdateVote(vote)
-	switch err {
-	case nil:
-		w.WriteHeader(http.StatusOK)
-		w.Write(models.ToBytes(thread))
-	case myerr.ThreadNotExists:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("thread {slug: %s, id: %d} not found", vote.ThreadSlug, vote.ThreadId)}))
-	case myerr.UserNotExist:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write(models.ToBytes(models.Error{Message: fmt.Sprintf("user %s not found", vote.Nickname)}))
+	thread, err := vd.voteUsecase.UpdateVote(r.Context(), vote)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, thread)
+	case errors.Is(err, myerr.ThreadNotExists):
+		response.Error(w, r, myerr.ThreadNotExists.WithMessage("thread {slug: %s, id: %d} not found", vote.ThreadSlug, vote.ThreadId))
+	case errors.Is(err, myerr.UserNotExist):
+		response.Error(w, r, myerr.UserNotExist.WithMessage("user %s not found", vote.Nickname))
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(models.ToBytes(models.Error{Message: err.Error()}))
+		response.Error(w, r, err)
 	}
 }
@@ -1,10 +1,13 @@
 package votes
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type VoteRepository interface {
-	InsertVote(vote *models.Vote) error
-	UpdateVote(vote *models.Vote) error
-	SelectThreadById(threadId int64) (*models.Thread, error)
-	SelectThread(vote *models.Vote) (int64, error)
+	InsertVote(ctx context.Context, vote *models.Vote) error
+	UpdateVote(ctx context.Context, vote *models.Vote) error
+	SelectThreadById(ctx context.Context, threadId int64) (*models.Thread, error)
+	SelectThread(ctx context.Context, vote *models.Vote) (int64, string, string, error)
 }
@@ -5,63 +5,63 @@ import (
 	"database/sql"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
 	"forum/internal/pkg/votes"
-	"log"
-	"regexp"
+	"time"
 )
 
 type VoteRepository struct {
 	db     *sql.DB
-	logger *log.Logger
+	logger *logger.Logger
 }
 
-func NewVoteRepository(db *sql.DB) votes.VoteRepository {
+func NewVoteRepository(db *sql.DB, lg *logger.Logger) votes.VoteRepository {
 	return &VoteRepository{
 		db:     db,
-		logger: log.Default(),
+		logger: lg,
 	}
 }
 
-func (vr *VoteRepository) SelectThread(vote *models.Vote) (int64, error) {
-	row := vr.db.QueryRow(
-		"SELECT id from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1",
+func (vr *VoteRepository) SelectThread(ctx context.Context, vote *models.Vote) (int64, string, string, error) {
+	defer metrics.ObserveQuery("votes", "SelectThread", time.Now())
+
+	var forum, status string
+	row := vr.db.QueryRowContext(
+		ctx,
+		"SELECT id, forum, status from threads WHERE 0 = $1 AND slug = $2 OR $2 = '' AND id = $1",
 		vote.ThreadId, vote.ThreadSlug)
-	err := row.Scan(&vote.ThreadId)
+	err := row.Scan(&vote.ThreadId, &forum, &status)
 	if err != nil {
-		res, _ := regexp.Match(".*no rows in result set.*", []byte(err.Error()))
-		if res {
-			return 0, myerr.ThreadNotExists
+		dbErr, known := myerr.FromDb(err, myerr.ThreadNotExists)
+		if !known {
+			vr.logger.Error(ctx, err.Error())
 		}
-		return 0, myerr.InternalDbError
+		return 0, "", "", dbErr
 	}
-	return vote.ThreadId, nil
+	return vote.ThreadId, forum, status, nil
 }
 
-func (vr *VoteRepository) InsertVote(vote *models.Vote) error {
-	tx, err := vr.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (vr *VoteRepository) InsertVote(ctx context.Context, vote *models.Vote) error {
+	defer metrics.ObserveQuery("votes", "InsertVote", time.Now())
+
+	tx, err := vr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
-	_, err = tx.Exec("INSERT INTO votes(voice, nickname, thread) VALUES ($2, $3, $1);", vote.ThreadId, vote.Voice, vote.Nickname)
+	_, err = tx.ExecContext(ctx, "INSERT INTO votes(voice, nickname, thread) VALUES ($2, $3, $1);", vote.ThreadId, vote.Voice, vote.Nickname)
 	if err != nil {
 		rollbackError := tx.Rollback()
 		if rollbackError != nil {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*votes_pkey.*", []byte(err.Error()))
-		if res {
-			return myerr.ThreadAlreadyExist
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			vr.logger.Error(ctx, err.Error())
 		}
-
-		res, _ = regexp.Match(".*votes_nickname_fkey.*", []byte(err.Error()))
-		if res {
-			return myerr.UserNotExist
-		}
-
-		vr.logger.Println(err.Error())
-		return myerr.InternalDbError
+		return dbErr
 	}
 
 	err = tx.Commit()
@@ -72,13 +72,16 @@ func (vr *VoteRepository) InsertVote(vote *models.Vote) error {
 	return nil
 }
 
-func (vr *VoteRepository) UpdateVote(vote *models.Vote) error {
-	tx, err := vr.db.BeginTx(context.Background(), &sql.TxOptions{})
+func (vr *VoteRepository) UpdateVote(ctx context.Context, vote *models.Vote) error {
+	defer metrics.ObserveQuery("votes", "UpdateVote", time.Now())
+
+	tx, err := vr.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return myerr.InternalDbError
 	}
 
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(
+		ctx,
 		"UPDATE votes SET voice = $1 WHERE nickname = $2 AND thread = $3;",
 		vote.Voice, vote.Nickname, vote.ThreadId)
 	if err != nil {
@@ -87,13 +90,11 @@ func (vr *VoteRepository) UpdateVote(vote *models.Vote) error {
 			return myerr.RollbackError
 		}
 
-		res, _ := regexp.Match(".*votes_nickname_fkey.*", []byte(err.Error()))
-		if res {
-			return myerr.UserNotExist
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			vr.logger.Error(ctx, err.Error())
 		}
-
-		vr.logger.Println(err.Error())
-		return myerr.InternalDbError
+		return dbErr
 	}
 
 	err = tx.Commit()
@@ -104,13 +105,15 @@ func (vr *VoteRepository) UpdateVote(vote *models.Vote) error {
 	return nil
 }
 
-func (vr *VoteRepository) SelectThreadById(threadId int64) (*models.Thread, error) {
+func (vr *VoteRepository) SelectThreadById(ctx context.Context, threadId int64) (*models.Thread, error) {
+	defer metrics.ObserveQuery("votes", "SelectThreadById", time.Now())
+
 	thread := &models.Thread{}
 
-	row := vr.db.QueryRow("SELECT id, title, author, forum, message, votes, slug, created FROM threads WHERE id = $1", threadId)
-	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+	row := vr.db.QueryRowContext(ctx, "SELECT id, title, author, forum, message, votes, slug, created, status, pinned FROM threads WHERE id = $1", threadId)
+	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.Status, &thread.Pinned)
 	if err != nil {
-		vr.logger.Println(err.Error())
+		vr.logger.Error(ctx, err.Error())
 		return nil, myerr.InternalDbError
 	}
 
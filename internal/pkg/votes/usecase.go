@@ -1,7 +1,10 @@
 package votes
 
-import "forum/internal/models"
+import (
+	"context"
+	"forum/internal/models"
+)
 
 type VoteUsecase interface {
-	UpdateVote(vote *models.Vote) (*models.Thread, error)
+	UpdateVote(ctx context.Context, vote *models.Vote) (*models.Thread, error)
 }
@@ -1,31 +1,52 @@
 package usecase
 
 import (
+	"context"
 	myerr "forum/internal/error"
 	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/bans"
 	"forum/internal/pkg/votes"
 )
 
 type VoteUsecase struct {
 	repo votes.VoteRepository
+	bans bans.BanChecker
 }
 
-func NewVoteUsecase(repo votes.VoteRepository) votes.VoteUsecase {
+func NewVoteUsecase(repo votes.VoteRepository, bans bans.BanChecker) votes.VoteUsecase {
 	return &VoteUsecase{
 		repo: repo,
+		bans: bans,
 	}
 }
 
-func (vu *VoteUsecase) UpdateVote(vote *models.Vote) (*models.Thread, error) {
-	_, err := vu.repo.SelectThread(vote)
+// UpdateVote votes as the authenticated user when the body names nobody.
+func (vu *VoteUsecase) UpdateVote(ctx context.Context, vote *models.Vote) (*models.Thread, error) {
+	if vote.Nickname == "" {
+		vote.Nickname = auth.Nickname(ctx)
+	}
+	err := auth.Authorize(ctx, vote.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	_, forum, status, err := vu.repo.SelectThread(ctx, vote)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.ThreadOpen {
+		return nil, myerr.ThreadClosed
+	}
+	err = vu.bans.CheckAllowed(ctx, forum, vote.Nickname)
 	if err != nil {
 		return nil, err
 	}
-	err = vu.repo.InsertVote(vote)
+	err = vu.repo.InsertVote(ctx, vote)
 	switch err {
 	case nil:
-	case myerr.ThreadAlreadyExist:
-		err = vu.repo.UpdateVote(vote)
+	case myerr.VoteAlreadyExist:
+		err = vu.repo.UpdateVote(ctx, vote)
 		if err != nil {
 			return nil, err
 		}
@@ -33,6 +54,6 @@ func (vu *VoteUsecase) UpdateVote(vote *models.Vote) (*models.Thread, error) {
 		return nil, err
 	}
 
-	thread, err := vu.repo.SelectThreadById(vote.ThreadId)
+	thread, err := vu.repo.SelectThreadById(ctx, vote.ThreadId)
 	return thread, err
 }
@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/response"
+	"forum/internal/pkg/webhooks"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookDelivery struct {
+	webhookUsecase webhooks.WebhookUsecase
+}
+
+func NewWebhookDelivery(webhookUsecase webhooks.WebhookUsecase) *WebhookDelivery {
+	return &WebhookDelivery{
+		webhookUsecase: webhookUsecase,
+	}
+}
+
+func (wd *WebhookDelivery) Routing(r *mux.Router) {
+	r.HandleFunc("/forum/{slug}/webhooks", wd.CreateWebhookHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/webhooks", wd.GetWebhooksHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/webhooks/{id:[0-9]+}", wd.DeleteWebhookHandler).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/webhooks/{id:[0-9]+}/deliveries", wd.GetDeliveriesHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/forum/{slug}/webhooks/{id:[0-9]+}/redeliver", wd.RedeliverHandler).Methods(http.MethodPost, http.MethodOptions)
+}
+
+func (wd *WebhookDelivery) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	input := &models.WebhookInput{}
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	err = json.Unmarshal(buf, input)
+	if err != nil {
+		response.Error(w, r, myerr.InvalidBody)
+		return
+	}
+
+	webhook, err := wd.webhookUsecase.CreateWebhook(r.Context(), slug, input)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusCreated, webhook)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (wd *WebhookDelivery) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	hooks, err := wd.webhookUsecase.GetWebhooks(r.Context(), slug)
+	switch {
+	case err == nil:
+		response.JSON(w, http.StatusOK, hooks)
+	case errors.Is(err, myerr.ForumNotExist):
+		response.Error(w, r, myerr.ForumNotExist.WithMessage("Can't find forum with slug: %s", slug))
+	default:
+		response.Error(w, r, err)
+	}
+}
+
+func (wd *WebhookDelivery) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	err := wd.webhookUsecase.DeleteWebhook(r.Context(), slug, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveriesHandler lists the latest delivery attempts, newest first.
+func (wd *WebhookDelivery) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if err != nil {
+		limit = 100
+	}
+
+	attempts, err := wd.webhookUsecase.GetAttempts(r.Context(), slug, id, limit)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, attempts)
+}
+
+func (wd *WebhookDelivery) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	requeued, err := wd.webhookUsecase.Redeliver(r.Context(), slug, id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]int64{"requeued": requeued})
+}
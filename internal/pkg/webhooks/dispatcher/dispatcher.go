@@ -0,0 +1,236 @@
+// Package dispatcher sends the webhook outbox: it leases due items, POSTs
+// them signed to their receivers and records every attempt.
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/webhooks"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 32
+	workers      = 4
+
+	// lease must outlast a POST with every retry of the client
+	lease          = 5 * time.Minute
+	requestTimeout = 10 * time.Second
+	queryTimeout   = 10 * time.Second
+
+	// an item is dead-lettered after maxAttempts; retries wait baseBackoff
+	// doubled after every failure, at most maxBackoff
+	maxAttempts = 8
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+
+	maxErrorLength = 500
+)
+
+// Headers of a webhook request. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by the webhook secret.
+const (
+	SignatureHeader = "X-Forum-Signature"
+	TimestampHeader = "X-Forum-Timestamp"
+	EventHeader     = "X-Forum-Event"
+	DeliveryHeader  = "X-Forum-Delivery"
+)
+
+type Dispatcher struct {
+	repo   webhooks.WebhookRepository
+	client *http.Client
+	logger *logger.Logger
+}
+
+// NewDispatcher sends with client, or when nil with a client bounded by the
+// request timeout that refuses to connect to non-public addresses, which a
+// host name may resolve to long after the webhook was checked.
+func NewDispatcher(repo webhooks.WebhookRepository, client *http.Client, lg *logger.Logger) *Dispatcher {
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{
+			Timeout:   requestTimeout,
+			KeepAlive: 30 * time.Second,
+			Control:   publicOnly,
+		}).DialContext
+		client = &http.Client{Timeout: requestTimeout, Transport: transport}
+	}
+	return &Dispatcher{
+		repo:   repo,
+		client: client,
+		logger: lg,
+	}
+}
+
+// Run polls the outbox until ctx is done. Items in flight when it stops
+// are picked up again once their lease expires.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches come back
+		for d.dispatchBatch(ctx) == batchSize && ctx.Err() == nil {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchBatch(ctx context.Context) int {
+	claimCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+	items, err := d.repo.ClaimOutbox(claimCtx, batchSize, lease)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Error(ctx, "webhook outbox claim failed", "error", err)
+		}
+		return 0
+	}
+
+	queue := make(chan *models.OutboxItem)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				d.dispatch(ctx, item)
+			}
+		}()
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+	return len(items)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, item *models.OutboxItem) {
+	attempt := &models.WebhookAttempt{
+		Delivery: item.Id,
+		Kind:     item.Kind,
+		Attempt:  item.Attempts + 1,
+	}
+
+	start := time.Now()
+	statusCode, err := d.send(ctx, item)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+
+	var retryAt *time.Time
+	switch {
+	case err == nil:
+		attempt.Status = models.OutboxDelivered
+	case attempt.Attempt >= maxAttempts:
+		attempt.Status = models.OutboxDead
+	default:
+		attempt.Status = models.OutboxPending
+		at := time.Now().Add(backoff(attempt.Attempt))
+		retryAt = &at
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		if len(attempt.Error) > maxErrorLength {
+			attempt.Error = attempt.Error[:maxErrorLength]
+		}
+	}
+
+	// record even when ctx is done, the attempt did happen
+	recordCtx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	if err = d.repo.RecordAttempt(recordCtx, item, attempt, retryAt); err != nil {
+		d.logger.Error(ctx, "webhook attempt not recorded", "delivery", item.Id, "error", err)
+	}
+}
+
+// send reports the status code of the receiver's answer and an error for
+// anything but 2xx.
+func (d *Dispatcher) send(ctx context.Context, item *models.OutboxItem) (int, error) {
+	body, err := json.Marshal(&models.WebhookPayload{
+		Delivery: item.Id,
+		Kind:     item.Kind,
+		Forum:    item.Forum,
+		Thread:   item.Thread,
+		Created:  item.Created,
+		Payload:  item.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(item.Secret, timestamp, body))
+	req.Header.Set(EventHeader, item.Kind)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(item.Id, 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain so the connection is reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the signature receivers verify a request with.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !webhooks.PublicIP(ip) {
+		return fmt.Errorf("refusing to connect to %s", host)
+	}
+	return nil
+}
+
+func backoff(attempt int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,220 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outbox is a WebhookRepository holding a single item that stays due until
+// it is delivered or dead.
+type outbox struct {
+	mu       sync.Mutex
+	item     *models.OutboxItem
+	status   string
+	attempts []*models.WebhookAttempt
+	retries  []*time.Time
+}
+
+func newOutbox(url string) *outbox {
+	return &outbox{
+		item: &models.OutboxItem{
+			Id:      7,
+			Webhook: 3,
+			Url:     url,
+			Secret:  "s3cret",
+			Forum:   "golang",
+			Thread:  42,
+			Kind:    models.EventPost,
+			Payload: json.RawMessage(`{"id":1}`),
+			Created: "2021-01-01T00:00:00.000Z",
+		},
+		status: models.OutboxPending,
+	}
+}
+
+func (o *outbox) InsertWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return nil
+}
+
+func (o *outbox) SelectWebhooks(ctx context.Context, forum string) ([]*models.Webhook, error) {
+	return nil, nil
+}
+
+func (o *outbox) DeleteWebhook(ctx context.Context, forum string, id int64) error {
+	return nil
+}
+
+func (o *outbox) SelectAttempts(ctx context.Context, forum string, id int64, limit int64) ([]*models.WebhookAttempt, error) {
+	return nil, nil
+}
+
+func (o *outbox) RequeueDead(ctx context.Context, forum string, id int64) (int64, error) {
+	return 0, nil
+}
+
+func (o *outbox) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxItem, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.status != models.OutboxPending {
+		return nil, nil
+	}
+	item := *o.item
+	return []*models.OutboxItem{&item}, nil
+}
+
+func (o *outbox) RecordAttempt(ctx context.Context, item *models.OutboxItem, attempt *models.WebhookAttempt, retryAt *time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.item.Attempts = attempt.Attempt
+	o.status = attempt.Status
+	o.attempts = append(o.attempts, attempt)
+	o.retries = append(o.retries, retryAt)
+	return nil
+}
+
+func TestDispatchSigned(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := newOutbox(receiver.URL)
+	d := NewDispatcher(repo, receiver.Client(), logger.New(ioutil.Discard, "error"))
+	if n := d.dispatchBatch(context.Background()); n != 1 {
+		t.Fatalf("dispatchBatch() = %d, want 1", n)
+	}
+
+	if got == nil {
+		t.Fatal("receiver got no request")
+	}
+	want := "sha256=" + Sign("s3cret", got.Header.Get(TimestampHeader), body)
+	if signature := got.Header.Get(SignatureHeader); signature != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, signature, want)
+	}
+	if kind := got.Header.Get(EventHeader); kind != models.EventPost {
+		t.Errorf("%s = %q, want %q", EventHeader, kind, models.EventPost)
+	}
+	if delivery := got.Header.Get(DeliveryHeader); delivery != "7" {
+		t.Errorf("%s = %q, want 7", DeliveryHeader, delivery)
+	}
+
+	payload := &models.WebhookPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		t.Fatalf("body is not a payload: %v", err)
+	}
+	if payload.Delivery != 7 || payload.Forum != "golang" || payload.Thread != 42 || string(payload.Payload) != `{"id":1}` {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	if len(repo.attempts) != 1 {
+		t.Fatalf("recorded %d attempts, want 1", len(repo.attempts))
+	}
+	attempt := repo.attempts[0]
+	if attempt.Status != models.OutboxDelivered || attempt.StatusCode != http.StatusNoContent || attempt.Attempt != 1 || attempt.Error != "" {
+		t.Errorf("attempt = %+v", attempt)
+	}
+	if repo.retries[0] != nil {
+		t.Errorf("delivered item scheduled for a retry at %v", repo.retries[0])
+	}
+}
+
+func TestDispatchDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	repo := newOutbox(receiver.URL)
+	d := NewDispatcher(repo, receiver.Client(), logger.New(ioutil.Discard, "error"))
+	for i := 0; i < maxAttempts+2; i++ {
+		d.dispatchBatch(context.Background())
+	}
+
+	if repo.status != models.OutboxDead {
+		t.Fatalf("status = %q, want %q", repo.status, models.OutboxDead)
+	}
+	if len(repo.attempts) != maxAttempts {
+		t.Fatalf("recorded %d attempts, want %d", len(repo.attempts), maxAttempts)
+	}
+	for i, attempt := range repo.attempts {
+		wantStatus := models.OutboxPending
+		if i == maxAttempts-1 {
+			wantStatus = models.OutboxDead
+		}
+		if attempt.Attempt != i+1 || attempt.Status != wantStatus || attempt.StatusCode != http.StatusInternalServerError {
+			t.Errorf("attempt %d = %+v", i+1, attempt)
+		}
+		if !strings.Contains(attempt.Error, "500") {
+			t.Errorf("attempt %d error = %q, want the receiver's status", i+1, attempt.Error)
+		}
+
+		retryAt := repo.retries[i]
+		if wantStatus == models.OutboxDead {
+			if retryAt != nil {
+				t.Errorf("dead item scheduled for a retry at %v", retryAt)
+			}
+			continue
+		}
+		if retryAt == nil {
+			t.Errorf("attempt %d: pending item has no retry", i+1)
+			continue
+		}
+		wait := time.Until(*retryAt)
+		if want := backoff(i + 1); wait > want || wait < want-time.Minute {
+			t.Errorf("attempt %d retries in %v, want about %v", i+1, wait, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{7, 640 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{30, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := newOutbox(receiver.URL)
+	d := NewDispatcher(repo, nil, logger.New(ioutil.Discard, "error"))
+	d.dispatchBatch(context.Background())
+
+	if len(repo.attempts) != 1 {
+		t.Fatalf("recorded %d attempts, want 1", len(repo.attempts))
+	}
+	attempt := repo.attempts[0]
+	if attempt.Status != models.OutboxPending || attempt.StatusCode != 0 || !strings.Contains(attempt.Error, "refusing to connect") {
+		t.Errorf("attempt = %+v, want a refused connection", attempt)
+	}
+}
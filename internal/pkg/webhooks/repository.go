@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"context"
+	"forum/internal/models"
+	"time"
+)
+
+type WebhookRepository interface {
+	InsertWebhook(ctx context.Context, webhook *models.Webhook) error
+	SelectWebhooks(ctx context.Context, forum string) ([]*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, forum string, id int64) error
+	SelectAttempts(ctx context.Context, forum string, id int64, limit int64) ([]*models.WebhookAttempt, error)
+	RequeueDead(ctx context.Context, forum string, id int64) (int64, error)
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxItem, error)
+	RecordAttempt(ctx context.Context, item *models.OutboxItem, attempt *models.WebhookAttempt, retryAt *time.Time) error
+}
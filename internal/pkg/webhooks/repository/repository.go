@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/logger"
+	"forum/internal/pkg/metrics"
+	"forum/internal/pkg/webhooks"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewWebhookRepository(db *sql.DB, lg *logger.Logger) webhooks.WebhookRepository {
+	return &WebhookRepository{
+		db:     db,
+		logger: lg,
+	}
+}
+
+func (wr *WebhookRepository) InsertWebhook(ctx context.Context, webhook *models.Webhook) error {
+	defer metrics.ObserveQuery("webhooks", "InsertWebhook", time.Now())
+
+	row := wr.db.QueryRowContext(
+		ctx,
+		`INSERT INTO webhooks (forum, url, secret, events)
+		 VALUES (COALESCE((SELECT slug FROM forum WHERE slug = $1), $1), $2, $3, $4)
+		 RETURNING id, forum, created;`,
+		webhook.Forum, webhook.Url, webhook.Secret, pq.Array(webhook.Events))
+	err := row.Scan(&webhook.Id, &webhook.Forum, &webhook.Created)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) SelectWebhooks(ctx context.Context, forum string) ([]*models.Webhook, error) {
+	defer metrics.ObserveQuery("webhooks", "SelectWebhooks", time.Now())
+
+	row := wr.db.QueryRowContext(ctx, "SELECT slug FROM forum WHERE slug = $1", forum)
+	err := row.Scan(&forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.ForumNotExist)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+
+	rows, err := wr.db.QueryContext(
+		ctx,
+		"SELECT id, forum, url, events, created FROM webhooks WHERE forum = $1 ORDER BY id;",
+		forum)
+	if err != nil {
+		wr.logger.Error(ctx, err.Error())
+		return nil, myerr.InternalDbError
+	}
+	defer rows.Close()
+
+	hooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		hook := &models.Webhook{}
+		err = rows.Scan(&hook.Id, &hook.Forum, &hook.Url, pq.Array(&hook.Events), &hook.Created)
+		if err != nil {
+			wr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func (wr *WebhookRepository) DeleteWebhook(ctx context.Context, forum string, id int64) error {
+	defer metrics.ObserveQuery("webhooks", "DeleteWebhook", time.Now())
+
+	res, err := wr.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND forum = $2;", id, forum)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.WebhookNotExist)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		wr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+	if deleted == 0 {
+		return myerr.WebhookNotExist
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) checkWebhook(ctx context.Context, forum string, id int64) error {
+	row := wr.db.QueryRowContext(ctx, "SELECT id FROM webhooks WHERE id = $1 AND forum = $2;", id, forum)
+	err := row.Scan(&id)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.WebhookNotExist)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) SelectAttempts(ctx context.Context, forum string, id int64, limit int64) ([]*models.WebhookAttempt, error) {
+	defer metrics.ObserveQuery("webhooks", "SelectAttempts", time.Now())
+
+	if err := wr.checkWebhook(ctx, forum, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := wr.db.QueryContext(
+		ctx,
+		`SELECT d.id, d.outbox, o.kind, d.attempt, d.status_code, d.error, d.duration_ms, o.status, d.created
+		 FROM webhook_deliveries d
+		 JOIN webhook_outbox o ON o.id = d.outbox
+		 WHERE d.webhook = $1
+		 ORDER BY d.id DESC
+		 LIMIT $2;`,
+		id, limit)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.WebhookNotExist)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	attempts := make([]*models.WebhookAttempt, 0)
+	for rows.Next() {
+		attempt := &models.WebhookAttempt{}
+		err = rows.Scan(&attempt.Id, &attempt.Delivery, &attempt.Kind, &attempt.Attempt, &attempt.StatusCode,
+			&attempt.Error, &attempt.DurationMs, &attempt.Status, &attempt.Created)
+		if err != nil {
+			wr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
+
+func (wr *WebhookRepository) RequeueDead(ctx context.Context, forum string, id int64) (int64, error) {
+	defer metrics.ObserveQuery("webhooks", "RequeueDead", time.Now())
+
+	if err := wr.checkWebhook(ctx, forum, id); err != nil {
+		return 0, err
+	}
+
+	res, err := wr.db.ExecContext(
+		ctx,
+		`UPDATE webhook_outbox SET
+			status = 'pending',
+			attempts = 0,
+			next_attempt = NOW()
+		 WHERE webhook = $1 AND status = 'dead';`,
+		id)
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.WebhookNotExist)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return 0, dbErr
+	}
+	requeued, _ := res.RowsAffected()
+	return requeued, nil
+}
+
+// ClaimOutbox leases due items by pushing their next attempt past the
+// lease, so other instances skip them while they are being sent.
+func (wr *WebhookRepository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxItem, error) {
+	defer metrics.ObserveQuery("webhooks", "ClaimOutbox", time.Now())
+
+	rows, err := wr.db.QueryContext(
+		ctx,
+		`UPDATE webhook_outbox o SET
+			next_attempt = NOW() + $2 * INTERVAL '1 millisecond'
+		 FROM webhooks w
+		 WHERE w.id = o.webhook AND o.id IN (
+			SELECT id FROM webhook_outbox
+			WHERE status = 'pending' AND next_attempt <= NOW()
+			ORDER BY next_attempt, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING o.id, o.webhook, w.url, w.secret, o.forum, o.thread, o.kind, o.payload, o.attempts, o.created;`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return nil, dbErr
+	}
+	defer rows.Close()
+
+	items := make([]*models.OutboxItem, 0)
+	for rows.Next() {
+		item := &models.OutboxItem{}
+		var payload []byte
+		err = rows.Scan(&item.Id, &item.Webhook, &item.Url, &item.Secret, &item.Forum, &item.Thread,
+			&item.Kind, &payload, &item.Attempts, &item.Created)
+		if err != nil {
+			wr.logger.Error(ctx, err.Error())
+			return nil, myerr.InternalDbError
+		}
+		item.Payload = payload
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// RecordAttempt logs the attempt and moves the item on: delivered when the
+// attempt succeeded, retried at retryAt or dead when retryAt is nil.
+func (wr *WebhookRepository) RecordAttempt(ctx context.Context, item *models.OutboxItem, attempt *models.WebhookAttempt, retryAt *time.Time) error {
+	defer metrics.ObserveQuery("webhooks", "RecordAttempt", time.Now())
+
+	tx, err := wr.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		wr.logger.Error(ctx, err.Error())
+		return myerr.InternalDbError
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO webhook_deliveries (outbox, webhook, attempt, status_code, error, duration_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6);`,
+		item.Id, item.Webhook, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err == nil {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE webhook_outbox SET
+				status = $2,
+				attempts = $3,
+				next_attempt = COALESCE($4, next_attempt)
+			 WHERE id = $1;`,
+			item.Id, attempt.Status, attempt.Attempt, retryAt)
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			return myerr.RollbackError
+		}
+		dbErr, known := myerr.FromDb(err, myerr.NoRows)
+		if !known {
+			wr.logger.Error(ctx, err.Error())
+		}
+		return dbErr
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return myerr.CommitError
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package webhooks
+
+import (
+	"net"
+	"strings"
+)
+
+// PublicHost tells whether a webhook may be sent to host. Loopback and
+// link-local addresses would let a webhook reach the service itself or the
+// metadata endpoints of its cloud, so they are refused.
+func PublicHost(host string) bool {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || PublicIP(ip)
+}
+
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
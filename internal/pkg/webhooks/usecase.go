@@ -0,0 +1,14 @@
+package webhooks
+
+import (
+	"context"
+	"forum/internal/models"
+)
+
+type WebhookUsecase interface {
+	CreateWebhook(ctx context.Context, forum string, input *models.WebhookInput) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context, forum string) ([]*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, forum string, id int64) error
+	GetAttempts(ctx context.Context, forum string, id int64, limit int64) ([]*models.WebhookAttempt, error)
+	Redeliver(ctx context.Context, forum string, id int64) (int64, error)
+}
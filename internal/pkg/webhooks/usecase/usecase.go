@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	myerr "forum/internal/error"
+	"forum/internal/models"
+	"forum/internal/pkg/auth"
+	"forum/internal/pkg/webhooks"
+	"net/url"
+)
+
+const secretBytes = 32
+
+type WebhookUsecase struct {
+	repo webhooks.WebhookRepository
+}
+
+func NewWebhookUsecase(repo webhooks.WebhookRepository) webhooks.WebhookUsecase {
+	return &WebhookUsecase{
+		repo: repo,
+	}
+}
+
+// CreateWebhook registers a receiver for the events of a forum. Without a
+// secret one is generated; either way it is returned only here.
+func (wu *WebhookUsecase) CreateWebhook(ctx context.Context, forum string, input *models.WebhookInput) (*models.Webhook, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, myerr.Forbidden
+	}
+
+	target, err := url.Parse(input.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, myerr.InvalidWebhook
+	}
+	if !webhooks.PublicHost(target.Hostname()) {
+		return nil, myerr.InvalidWebhook.WithMessage("webhook url must not point to a loopback or link-local address")
+	}
+	events := make([]string, 0, len(input.Events))
+	for _, kind := range input.Events {
+		switch kind {
+		case models.EventThread, models.EventPost, models.EventPostEdit, models.EventVotes:
+			events = append(events, kind)
+		default:
+			return nil, myerr.InvalidWebhook.WithMessage("unknown event %q", kind)
+		}
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		buf := make([]byte, secretBytes)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &models.Webhook{
+		Forum:  forum,
+		Url:    target.String(),
+		Events: events,
+		Secret: secret,
+	}
+	err = wu.repo.InsertWebhook(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (wu *WebhookUsecase) GetWebhooks(ctx context.Context, forum string) ([]*models.Webhook, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, myerr.Forbidden
+	}
+	hooks, err := wu.repo.SelectWebhooks(ctx, forum)
+	return hooks, err
+}
+
+func (wu *WebhookUsecase) DeleteWebhook(ctx context.Context, forum string, id int64) error {
+	if !auth.IsAdmin(ctx) {
+		return myerr.Forbidden
+	}
+	return wu.repo.DeleteWebhook(ctx, forum, id)
+}
+
+func (wu *WebhookUsecase) GetAttempts(ctx context.Context, forum string, id int64, limit int64) ([]*models.WebhookAttempt, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, myerr.Forbidden
+	}
+	attempts, err := wu.repo.SelectAttempts(ctx, forum, id, limit)
+	return attempts, err
+}
+
+// Redeliver puts the dead-lettered items of a webhook back into the queue.
+func (wu *WebhookUsecase) Redeliver(ctx context.Context, forum string, id int64) (int64, error) {
+	if !auth.IsAdmin(ctx) {
+		return 0, myerr.Forbidden
+	}
+	requeued, err := wu.repo.RequeueDead(ctx, forum, id)
+	return requeued, err
+}